@@ -0,0 +1,298 @@
+// Package amxtest provides an in-process fake of the AMX loginByPassword
+// and getAllSecInfo endpoints for tests.
+package amxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	amxapi "main.go/amx"
+)
+
+const (
+	LoginPath   = "/login/v2/loginByPassword"
+	SecInfoPath = "/report/exchange/v2/getAllSecInfo"
+
+	UserID   = "TESTUSER"
+	Password = "TEST#PASS"
+)
+
+// Fault changes the response of a page. Status answers with that HTTP
+// status, Body replaces the response body and Delay holds the response
+// back. Times limits the fault to the first requests of the page, zero
+// applies it to every request.
+type Fault struct {
+	Status int
+	Body   string
+	Delay  time.Duration
+	Times  int
+}
+
+type pageKey struct {
+	exchange string
+	page     int
+}
+
+// Server serves the pages set with SetPages, SetRecords or LoadFixtures.
+// Pages are numbered from 1, an exchange without pages answers with one
+// empty last page.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	token    string
+	tokens   int
+	pages    map[string][][]json.RawMessage
+	faults   map[pageKey]*Fault
+	logins   int
+	requests map[pageKey]int
+}
+
+func NewServer() *Server {
+
+	s := &Server{
+		pages:    make(map[string][][]json.RawMessage),
+		faults:   make(map[pageKey]*Fault),
+		requests: make(map[pageKey]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(LoginPath, s.login)
+	mux.HandleFunc(SecInfoPath, s.secInfo)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Config returns a client configuration for the fake with short backoffs.
+func (s *Server) Config() amxapi.Config {
+
+	return amxapi.Config{
+		LoginURL:    s.LoginURL(),
+		SecInfoURL:  s.SecInfoURL(),
+		UserID:      UserID,
+		Password:    Password,
+		Timeout:     5 * time.Second,
+		MaxRetries:  2,
+		BackoffBase: time.Millisecond,
+		BackoffMax:  5 * time.Millisecond,
+	}
+}
+
+func (s *Server) LoginURL() string {
+	return s.URL + LoginPath
+}
+
+// SecInfoURL is the page url prefix, the client appends the query.
+func (s *Server) SecInfoURL() string {
+	return s.URL + SecInfoPath + "?"
+}
+
+// SetPages replaces the pages of an exchange.
+func (s *Server) SetPages(exchange string, pages ...[]json.RawMessage) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages[exchange] = pages
+}
+
+// SetRecords splits records into pages of pageSize records.
+func (s *Server) SetRecords(exchange string, pageSize int, records []json.RawMessage) {
+
+	if pageSize <= 0 {
+		pageSize = len(records)
+	}
+	var pages [][]json.RawMessage
+	for start := 0; start < len(records); start += pageSize {
+		end := start + pageSize
+		if end > len(records) {
+			end = len(records)
+		}
+		pages = append(pages, records[start:end])
+	}
+	s.SetPages(exchange, pages...)
+}
+
+// LoadFixtures reads every <exchange>.json file of dir, each a JSON array
+// of getAllSecInfo records, into pages of pageSize records.
+func (s *Server) LoadFixtures(dir string, pageSize int) error {
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var records []json.RawMessage
+		if err := json.Unmarshal(data, &records); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		s.SetRecords(strings.TrimSuffix(filepath.Base(file), ".json"), pageSize, records)
+	}
+	return nil
+}
+
+// Inject sets the fault of a page.
+func (s *Server) Inject(exchange string, page int, fault Fault) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[pageKey{exchange, page}] = &fault
+}
+
+// Fail answers the first times requests of a page with status, every
+// request when times is zero.
+func (s *Server) Fail(exchange string, page, status, times int) {
+	s.Inject(exchange, page, Fault{Status: status, Times: times})
+}
+
+// Slow holds every response of a page back by delay.
+func (s *Server) Slow(exchange string, page int, delay time.Duration) {
+	s.Inject(exchange, page, Fault{Delay: delay})
+}
+
+// Malformed appends records that are not valid getAllSecInfo records to a
+// page.
+func (s *Server) Malformed(exchange string, page int, records ...string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pages := s.pages[exchange]
+	for len(pages) < page {
+		pages = append(pages, nil)
+	}
+	for _, r := range records {
+		pages[page-1] = append(pages[page-1], json.RawMessage(r))
+	}
+	s.pages[exchange] = pages
+}
+
+// ExpireToken invalidates the issued token, the next page answers 401.
+func (s *Server) ExpireToken() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+// Logins returns the number of successful logins.
+func (s *Server) Logins() int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// Requests returns the number of requests of every page of an exchange.
+func (s *Server) Requests(exchange string) int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for key, n := range s.requests {
+		if key.exchange == exchange {
+			total += n
+		}
+	}
+	return total
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+
+	var req amxapi.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.Method != http.MethodPost {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.UserID != UserID || req.PassOrPin != Password {
+		writeJSON(w, map[string]interface{}{"status": false, "message": "Invalid Credentials", "errorcode": "AB1007", "data": nil})
+		return
+	}
+	s.tokens++
+	s.token = "token-" + strconv.Itoa(s.tokens)
+	s.logins++
+	writeJSON(w, map[string]interface{}{"status": true, "message": "SUCCESS", "errorcode": "", "data": map[string]string{"accesstoken": s.token}})
+}
+
+func (s *Server) secInfo(w http.ResponseWriter, r *http.Request) {
+
+	exchange := r.URL.Query().Get("exchange")
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		http.Error(w, "invalid page", http.StatusBadRequest)
+		return
+	}
+	key := pageKey{exchange, page}
+
+	s.mu.Lock()
+	s.requests[key]++
+	authorized := s.token != "" && r.Header.Get("Authorization") == "Bearer "+s.token
+	var fault Fault
+	if f, ok := s.faults[key]; ok && (f.Times == 0 || s.requests[key] <= f.Times) {
+		fault = *f
+	}
+	pages := s.pages[exchange]
+	var records []json.RawMessage
+	if page <= len(pages) {
+		records = pages[page-1]
+	}
+	last := page >= len(pages)
+	s.mu.Unlock()
+
+	if fault.Delay > 0 {
+		select {
+		case <-time.After(fault.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if !authorized {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if fault.Status != 0 {
+		http.Error(w, http.StatusText(fault.Status), fault.Status)
+		return
+	}
+	if fault.Body != "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(fault.Body))
+		return
+	}
+
+	if records == nil {
+		records = []json.RawMessage{}
+	}
+	next := page + 1
+	if last {
+		next = page
+	}
+	writeJSON(w, map[string]interface{}{
+		"status":    true,
+		"message":   "SUCCESS",
+		"errorcode": "",
+		"data":      map[string]interface{}{"hasLastPage": last, "nextPage": next, "data": records},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,175 @@
+package amx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"main.go/internal/httpapi"
+)
+
+type Config struct {
+	LoginURL    string
+	SecInfoURL  string
+	UserID      string
+	Password    string
+	Timeout     time.Duration
+	MaxRetries  int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// Error is returned for every failed AMX call.
+type Error = httpapi.Error
+
+type Client struct {
+	cfg  Config
+	http *httpapi.Client
+
+	mu    sync.Mutex
+	token string
+
+	// login serializes the re-logins of concurrent page requests
+	login sync.Mutex
+}
+
+func NewClient(cfg Config) *Client {
+
+	return &Client{cfg: cfg, http: httpapi.NewClient(httpapi.Config{
+		Name:        "AMX",
+		Timeout:     cfg.Timeout,
+		MaxRetries:  cfg.MaxRetries,
+		BackoffBase: cfg.BackoffBase,
+		BackoffMax:  cfg.BackoffMax,
+	})}
+}
+
+// SetToken sets the bearer token used for getAllSecInfo.
+func (c *Client) SetToken(token string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+func (c *Client) currentToken() string {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// Login calls loginByPassword and keeps the access token for later calls.
+func (c *Client) Login(ctx context.Context) (string, error) {
+
+	body, _ := json.Marshal(LoginRequest{UserID: c.cfg.UserID, PassOrPin: c.cfg.Password})
+
+	var res LoginResponse
+	err := c.http.Do(ctx, "login", c.cfg.LoginURL, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.LoginURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-SourceID", "2")
+		req.Header.Set("X-Platform", "MSIL")
+		req.Header.Set("X-DeviceID", "MSIL-MW")
+		req.Header.Set("X-UserType", "1")
+		req.Header.Set("X-OperatingSystem", "Linux")
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	}, &res)
+	if err != nil {
+		return "", err
+	}
+
+	if !httpapi.Success(res.Message) || res.Data.AccessToken == "" {
+		return "", &Error{Op: "login", URL: c.cfg.LoginURL, Code: res.ErrorCode.String(), Message: res.Message.String()}
+	}
+
+	c.SetToken(res.Data.AccessToken)
+	return res.Data.AccessToken, nil
+}
+
+// SecInfo fetches one page of getAllSecInfo for an exchange segment. An
+// expired token is refreshed with one re-login. The client is safe for
+// concurrent use.
+func (c *Client) SecInfo(ctx context.Context, exchange string, page int) (*SecInfoPage, error) {
+
+	pageUrl := c.cfg.SecInfoURL + "exchange=" + url.QueryEscape(exchange) + "&page=" + strconv.Itoa(page)
+	op := fmt.Sprintf("getAllSecInfo %s page %d", exchange, page)
+
+	if c.currentToken() == "" {
+		if err := c.relogin(ctx, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	var body json.RawMessage
+	var used string
+	request := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		used = c.currentToken()
+		req.Header.Set("Authorization", "Bearer "+used)
+		return req, nil
+	}
+
+	err := c.http.Do(ctx, op, pageUrl, request, &body)
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		log.Warn().Str("Exchange", exchange).Int("Page", page).Msg("AMX token expired, logging in again")
+		if lErr := c.relogin(ctx, used); lErr != nil {
+			return nil, lErr
+		}
+		err = c.http.Do(ctx, op, pageUrl, request, &body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := DecodeSecInfo(body)
+	if err != nil {
+		return nil, &Error{Op: op, URL: pageUrl, StatusCode: http.StatusOK, Err: err}
+	}
+
+	if !httpapi.Success(res.Message) {
+		return nil, &Error{Op: op, URL: pageUrl, Code: res.ErrorCode.String(), Message: res.Message.String()}
+	}
+	return &res.Data, nil
+}
+
+// relogin logs in again unless a concurrent request already replaced the
+// expired token.
+func (c *Client) relogin(ctx context.Context, expired string) error {
+
+	c.login.Lock()
+	defer c.login.Unlock()
+
+	if c.currentToken() != expired {
+		return nil
+	}
+	_, err := c.Login(ctx)
+	return err
+}
+
+// DecodeSecInfo decodes a getAllSecInfo response body and keeps the body
+// with the page.
+func DecodeSecInfo(body []byte) (*SecInfoResponse, error) {
+
+	var res SecInfoResponse
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, fmt.Errorf("%w: %v", httpapi.ErrDecode, err)
+	}
+	res.Data.Raw = body
+	return &res, nil
+}
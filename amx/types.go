@@ -0,0 +1,63 @@
+package amx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"main.go/internal/httpapi"
+)
+
+type LoginRequest struct {
+	UserID    string `json:"userid"`
+	PassOrPin string `json:"passorpin"`
+}
+
+type LoginResponse struct {
+	Status    Text      `json:"status"`
+	Message   Text      `json:"message"`
+	ErrorCode Text      `json:"errorcode"`
+	Data      LoginData `json:"data"`
+}
+
+type LoginData struct {
+	AccessToken string `json:"accesstoken"`
+}
+
+type SecInfoResponse struct {
+	Status    Text        `json:"status"`
+	Message   Text        `json:"message"`
+	ErrorCode Text        `json:"errorcode"`
+	Data      SecInfoPage `json:"data"`
+}
+
+// SecInfoPage is one page of getAllSecInfo. Records are kept raw so a
+// malformed record only fails its own decode, not the whole page.
+type SecInfoPage struct {
+	HasLastPage bool              `json:"hasLastPage"`
+	NextPage    json.Number       `json:"nextPage"`
+	Records     []json.RawMessage `json:"data"`
+
+	// Raw is the response body the page was decoded from.
+	Raw []byte `json:"-"`
+}
+
+// Text accepts a JSON string, number, bool or null.
+type Text = httpapi.Text
+
+// DecodeRecord decodes a raw getAllSecInfo record keeping numbers as
+// json.Number so no precision is lost before the scrip decoder sees them.
+func DecodeRecord(raw json.RawMessage) (map[string]interface{}, error) {
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var record map[string]interface{}
+	if err := dec.Decode(&record); err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errors.New("record is null")
+	}
+	return record, nil
+}
@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ManifestFile is the name of the manifest in a run directory.
+const ManifestFile = "manifest.json"
+
+// Page is one archived getAllSecInfo response. SHA256 is the hash of the
+// uncompressed response body.
+type Page struct {
+	Segment   string    `json:"segment"`
+	Page      int       `json:"page"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	File      string    `json:"file"`
+	Bytes     int       `json:"bytes"`
+	SHA256    string    `json:"sha256"`
+}
+
+type Manifest struct {
+	RunID     string    `json:"runId"`
+	CreatedAt time.Time `json:"createdAt"`
+	Pages     []Page    `json:"pages"`
+}
+
+// Writer stores the pages of one run gzip compressed under its directory.
+type Writer struct {
+	dir string
+
+	mu       sync.Mutex
+	manifest Manifest
+}
+
+func NewWriter(dir, runID string) (*Writer, error) {
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Writer{dir: dir, manifest: Manifest{RunID: runID, CreatedAt: time.Now()}}, nil
+}
+
+// Add archives the response body of a page.
+func (w *Writer) Add(segment string, page int, body []byte) error {
+
+	name := filepath.Join(segment, fmt.Sprintf("page-%05d.json.gz", page))
+	path := filepath.Join(w.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.manifest.Pages = append(w.manifest.Pages, Page{
+		Segment:   segment,
+		Page:      page,
+		FetchedAt: time.Now(),
+		File:      filepath.ToSlash(name),
+		Bytes:     len(body),
+		SHA256:    hex.EncodeToString(sum[:]),
+	})
+	return nil
+}
+
+// Close writes the manifest.
+func (w *Writer) Close() error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(w.dir, ManifestFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Reader reads the pages of an archived run.
+type Reader struct {
+	dir      string
+	Manifest Manifest
+}
+
+func Open(dir string) (*Reader, error) {
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{dir: dir}
+	if err := json.Unmarshal(data, &r.Manifest); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	return r, nil
+}
+
+// Pages returns the archived pages of a segment in page order.
+func (r *Reader) Pages(segment string) []Page {
+
+	var pages []Page
+	for _, p := range r.Manifest.Pages {
+		if p.Segment == segment {
+			pages = append(pages, p)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].Page < pages[j].Page })
+	return pages
+}
+
+// Read returns the response body of a page after checking its hash.
+func (r *Reader) Read(p Page) ([]byte, error) {
+
+	f, err := os.Open(filepath.Join(r.dir, filepath.FromSlash(p.File)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.File, err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.File, err)
+	}
+
+	sum := sha256.Sum256(body)
+	if hex.EncodeToString(sum[:]) != p.SHA256 {
+		return nil, fmt.Errorf("%s: hash mismatch, archive is corrupt", p.File)
+	}
+	return body, nil
+}
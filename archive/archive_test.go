@@ -0,0 +1,112 @@
+package archive_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"main.go/archive"
+)
+
+func write(t *testing.T, dir string, pages map[string][]string) {
+
+	t.Helper()
+	w, err := archive.NewWriter(dir, "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for segment, bodies := range pages {
+		// added last page first, as parallel fetchers may
+		for i := len(bodies) - 1; i >= 0; i-- {
+			if err := w.Add(segment, i+1, []byte(bodies[i])); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	dir := t.TempDir()
+	pages := map[string][]string{
+		"nse_fo": {`{"page": 1}`, `{"page": 2}`, `{"page": 3}`},
+		"nse_cm": {`{"page": 1}`},
+	}
+	write(t, dir, pages)
+
+	r, err := archive.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Manifest.RunID != "run-1" || len(r.Manifest.Pages) != 4 {
+		t.Errorf("manifest run %q with %d pages", r.Manifest.RunID, len(r.Manifest.Pages))
+	}
+
+	for segment, bodies := range pages {
+		read := r.Pages(segment)
+		if len(read) != len(bodies) {
+			t.Fatalf("%s: %d pages, want %d", segment, len(read), len(bodies))
+		}
+		for i, p := range read {
+			if p.Page != i+1 || p.Segment != segment || p.Bytes != len(bodies[i]) {
+				t.Errorf("%s: page %+v at %d", segment, p, i)
+			}
+			body, err := r.Read(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != bodies[i] {
+				t.Errorf("%s page %d: %s, want %s", segment, p.Page, body, bodies[i])
+			}
+		}
+	}
+	if pages := r.Pages("mcx_fo"); len(pages) != 0 {
+		t.Errorf("pages of a segment not archived: %v", pages)
+	}
+}
+
+func TestHashMismatch(t *testing.T) {
+
+	dir := t.TempDir()
+	write(t, dir, map[string][]string{"nse_fo": {`{"records": [1]}`}})
+
+	r, err := archive.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := r.Pages("nse_fo")[0]
+
+	// a valid gzip stream with other content
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"records": [2]}`))
+	gz.Close()
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(p.File)), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Read(p); err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+		t.Errorf("Read() error %v, want a hash mismatch", err)
+	}
+
+	// and one that is not gzip at all
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(p.File)), []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Read(p); err == nil {
+		t.Error("Read() accepted a page that is not gzip")
+	}
+}
+
+func TestOpenWithoutManifest(t *testing.T) {
+
+	if _, err := archive.Open(t.TempDir()); err == nil {
+		t.Error("Open() accepted a directory without a manifest")
+	}
+}
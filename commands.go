@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"main.go/constants"
+	service "main.go/services"
+)
+
+// backUps runs the list, inspect and prune actions of the backups command.
+func backUps(amx_config *service.AMXConfig, action, runID string) error {
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer out.Flush()
+
+	switch action {
+	case "", "list":
+		generations, err := service.AMXScripmaster.List_BackUps(amx_config)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "RUN ID\tTAKEN AT\tROWS")
+		for _, g := range generations {
+			fmt.Fprintf(out, "%s\t%s\t%d\n", g.RunID, g.TakenAt.Format(constants.RunTimeFormat), g.Rows)
+		}
+	case "inspect":
+		counts, err := service.AMXScripmaster.Inspect_BackUp(amx_config, runID)
+		if err != nil {
+			return err
+		}
+		segments := make([]string, 0, len(counts))
+		for segment := range counts {
+			segments = append(segments, segment)
+		}
+		sort.Strings(segments)
+		fmt.Fprintln(out, "MARKET SEGMENT\tROWS")
+		for _, segment := range segments {
+			fmt.Fprintf(out, "%s\t%d\n", segment, counts[segment])
+		}
+	case "prune":
+		pruned, err := service.AMXScripmaster.Prune_BackUps(amx_config)
+		for _, runID := range pruned {
+			fmt.Fprintf(out, "pruned\t%s\n", runID)
+		}
+		return err
+	default:
+		return &service.StepError{Class: service.ValidationFailure, Step: "backups", Details: "expected list, inspect or prune", Err: fmt.Errorf("unknown backups action %q", action)}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package commodity derives the contract specification of MCX and NCDEX
+// contracts from the general and price multipliers of getAllSecInfo.
+package commodity
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"main.go/decimal"
+	"main.go/entities"
+)
+
+// Rational is a fraction reduced to lowest terms with a positive
+// denominator. The zero value is 0/1.
+type Rational struct {
+	num, den int64
+}
+
+// MultiplierScale is the number of decimals a multiplier is written with.
+const MultiplierScale = 6
+
+// One is 1/1, the multiplier of contracts without a specification.
+var One = Rational{num: 1, den: 1}
+
+// NewRational returns num/den reduced.
+func NewRational(num, den int64) (Rational, error) {
+
+	if den == 0 {
+		return Rational{}, fmt.Errorf("zero denominator in %d/%d", num, den)
+	}
+	return fromRat(new(big.Rat).SetFrac64(num, den))
+}
+
+func fromRat(r *big.Rat) (Rational, error) {
+
+	if !r.Num().IsInt64() || !r.Denom().IsInt64() {
+		return Rational{}, fmt.Errorf("%s overflows int64", r.RatString())
+	}
+	return Rational{num: r.Num().Int64(), den: r.Denom().Int64()}, nil
+}
+
+func (r Rational) Num() int64 {
+	return r.num
+}
+
+func (r Rational) Den() int64 {
+
+	if r.den == 0 {
+		return 1
+	}
+	return r.den
+}
+
+func (r Rational) rat() *big.Rat {
+	return new(big.Rat).SetFrac64(r.num, r.Den())
+}
+
+// Mul returns the reduced product.
+func (r Rational) Mul(o Rational) (Rational, error) {
+	return fromRat(new(big.Rat).Mul(r.rat(), o.rat()))
+}
+
+// Decimal returns r rounded half away from zero to scale decimals.
+func (r Rational) Decimal(scale int) decimal.Decimal {
+
+	d, _ := decimal.New(r.num, 0).Div(r.Den(), scale)
+	return d
+}
+
+func (r Rational) String() string {
+	return strconv.FormatInt(r.num, 10) + "/" + strconv.FormatInt(r.Den(), 10)
+}
+
+// Spec is the contract specification of a commodity contract.
+//
+//	General       genNum/genDen, the quantity of one unit of the contract
+//	Price         priceNum/priceDen, the value of one price point
+//	Multiplier    General * Price, stored as the price numerator and
+//	              denominator of the master
+//	LotMultiplier Multiplier * minimum lot, the value of one lot per price
+//	              point
+//	QuotationUnit the quantity a price is quoted for, like "10 GRMS"
+//	DeliveryUnit  the unit the contract is delivered in
+//	Invalid       the pairs with a zero or negative term, empty when both
+//	              are valid
+type Spec struct {
+	General       Rational
+	Price         Rational
+	Multiplier    Rational
+	LotMultiplier Rational
+	QuotationUnit string
+	DeliveryUnit  string
+	Invalid       string
+}
+
+// New derives the specification of a contract. A pair sent as 0/0 is not
+// set and taken as 1/1. A pair with a zero or negative term is named in
+// Invalid and the contract keeps the multiplier 1/1, as the master always
+// gave such contracts. Only a multiplier beyond int64 is an error.
+func New(s entities.Scrip) (Spec, error) {
+
+	general, genOK := pair(s.GenNum, s.GenDen)
+	price, priceOK := pair(s.PriceNum, s.PriceDen)
+
+	var invalid []string
+	if !genOK {
+		invalid = append(invalid, fmt.Sprintf("genNum/genDen %d/%d", s.GenNum, s.GenDen))
+	}
+	if !priceOK {
+		invalid = append(invalid, fmt.Sprintf("priceNum/priceDen %d/%d", s.PriceNum, s.PriceDen))
+	}
+
+	var err error
+	spec := Spec{General: general, Price: price, Multiplier: One, Invalid: strings.Join(invalid, ", ")}
+	if spec.Invalid == "" {
+		if spec.Multiplier, err = general.Mul(price); err != nil {
+			return Spec{}, fmt.Errorf("multiplier: %w", err)
+		}
+	}
+	spec.LotMultiplier = spec.Multiplier
+	if s.MinimumLot > 0 {
+		lot := Rational{num: s.MinimumLot, den: 1}
+		if spec.LotMultiplier, err = spec.Multiplier.Mul(lot); err != nil {
+			return Spec{}, fmt.Errorf("lot multiplier: %w", err)
+		}
+	}
+
+	units := strings.TrimSpace(s.QtyUnits)
+	spec.QuotationUnit = units
+	if s.PriceQuotUnit > 0 && units != "" {
+		spec.QuotationUnit = strconv.FormatInt(s.PriceQuotUnit, 10) + " " + units
+	}
+	spec.DeliveryUnit = strings.TrimSpace(s.DeliveryUnit)
+	if spec.DeliveryUnit == "" {
+		spec.DeliveryUnit = units
+	}
+	return spec, nil
+}
+
+// pair returns num/den, or 1/1 and false when a term is zero or negative.
+func pair(num, den int64) (Rational, bool) {
+
+	if num == 0 && den == 0 {
+		return One, true
+	}
+	if num <= 0 || den <= 0 {
+		return One, false
+	}
+	r, err := NewRational(num, den)
+	return r, err == nil
+}
@@ -0,0 +1,124 @@
+package commodity_test
+
+import (
+	"math"
+	"testing"
+
+	"main.go/commodity"
+	"main.go/entities"
+)
+
+func TestNew(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		scrip         entities.Scrip
+		multiplier    string
+		lotMultiplier string
+		quotation     string
+		delivery      string
+		invalid       string
+		wantErr       bool
+	}{
+		{
+			name:       "gold",
+			scrip:      entities.Scrip{GenNum: 1, GenDen: 1, PriceNum: 100, PriceDen: 1, MinimumLot: 1, QtyUnits: "KGS", DeliveryUnit: "KGS"},
+			multiplier: "100/1", lotMultiplier: "100.000000", quotation: "KGS", delivery: "KGS",
+		},
+		{
+			name:       "fraction of a unit",
+			scrip:      entities.Scrip{GenNum: 1, GenDen: 100, PriceNum: 1, PriceDen: 1, MinimumLot: 1},
+			multiplier: "1/100", lotMultiplier: "0.010000",
+		},
+		{
+			name:       "reduced",
+			scrip:      entities.Scrip{GenNum: 10, GenDen: 1000, PriceNum: 1, PriceDen: 1, MinimumLot: 5},
+			multiplier: "1/100", lotMultiplier: "0.050000",
+		},
+		{
+			name:       "price and general cancel",
+			scrip:      entities.Scrip{GenNum: 1, GenDen: 10, PriceNum: 100, PriceDen: 1, MinimumLot: 30},
+			multiplier: "10/1", lotMultiplier: "300.000000",
+		},
+		{
+			name:       "repeating lot multiplier",
+			scrip:      entities.Scrip{GenNum: 1, GenDen: 3, PriceNum: 1, PriceDen: 1, MinimumLot: 2},
+			multiplier: "1/3", lotMultiplier: "0.666667",
+		},
+		{
+			name:       "quotation unit",
+			scrip:      entities.Scrip{GenNum: 1, GenDen: 1, PriceNum: 1, PriceDen: 1, PriceQuotUnit: 10, QtyUnits: "GRMS"},
+			multiplier: "1/1", lotMultiplier: "1.000000", quotation: "10 GRMS", delivery: "GRMS",
+		},
+		{
+			name:       "unset pairs",
+			scrip:      entities.Scrip{MinimumLot: 1},
+			multiplier: "1/1", lotMultiplier: "1.000000",
+		},
+		{
+			name:       "zero denominator",
+			scrip:      entities.Scrip{GenNum: 5, PriceNum: 100, PriceDen: 1, MinimumLot: 10},
+			multiplier: "1/1", lotMultiplier: "10.000000", invalid: "genNum/genDen 5/0",
+		},
+		{
+			name:       "zero numerator",
+			scrip:      entities.Scrip{GenNum: 1, GenDen: 100, PriceDen: 100},
+			multiplier: "1/1", lotMultiplier: "1.000000", invalid: "priceNum/priceDen 0/100",
+		},
+		{
+			name:       "negative",
+			scrip:      entities.Scrip{GenNum: -1, GenDen: 1, PriceNum: 1, PriceDen: -1},
+			multiplier: "1/1", lotMultiplier: "1.000000", invalid: "genNum/genDen -1/1, priceNum/priceDen 1/-1",
+		},
+		{name: "overflow", scrip: entities.Scrip{GenNum: math.MaxInt64, GenDen: 1, PriceNum: 2, PriceDen: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := commodity.New(tt.scrip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := spec.Multiplier.String(); got != tt.multiplier {
+				t.Errorf("multiplier %s, want %s", got, tt.multiplier)
+			}
+			if got := spec.LotMultiplier.Decimal(commodity.MultiplierScale).String(); got != tt.lotMultiplier {
+				t.Errorf("lot multiplier %s, want %s", got, tt.lotMultiplier)
+			}
+			if spec.Invalid != tt.invalid {
+				t.Errorf("invalid %q, want %q", spec.Invalid, tt.invalid)
+			}
+			if spec.QuotationUnit != tt.quotation || spec.DeliveryUnit != tt.delivery {
+				t.Errorf("units %q/%q, want %q/%q", spec.QuotationUnit, spec.DeliveryUnit, tt.quotation, tt.delivery)
+			}
+		})
+	}
+}
+
+func TestNewRational(t *testing.T) {
+
+	tests := []struct {
+		num, den int64
+		want     string
+		wantErr  bool
+	}{
+		{10, 1000, "1/100", false},
+		{6, -4, "-3/2", false},
+		{0, 7, "0/1", false},
+		{1, 0, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := commodity.NewRational(tt.num, tt.den)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewRational(%d, %d) error = %v", tt.num, tt.den, err)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("NewRational(%d, %d) = %s, want %s", tt.num, tt.den, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package constants
 const (
 	ApplicationConfig = "application.yaml"
 	DatabaseConfig    = "database.yaml"
+	RulesConfig       = "rules.yaml"
 	APIConfig         = "config.json"
 )
 
@@ -18,24 +19,52 @@ const (
 
 // db constants
 const (
-	Server           = "server"
-	User             = "user"
-	Password         = "password"
-	Port             = "port"
-	Retry            = "retry"
-	Database         = "database"
-	TimeFormat       = "Jan 02 2006 03:04PM"
-	MatDateTimeFomat = "2006/01/02 15:04"
-	ExpFormat        = "02 Jan 2006"
-	LogTimeFormat    = "2006-01-02-15:04"
-	SQL              = "sqlserver"
-	EQInsertQuery    = "eqDataInsertion"
-	DERInsertQuery   = "dervDataInsertion"
-	BackUpProcedure  = "backUpProc"
-	DeleteDerivative = "deleteDervProc"
-	DeleteEquity     = "deleteEQProc"
-	MarketCapQuery   = "marketCapProc"
-	StockIDQuery     = "stockIDUpdate"
+	Server            = "server"
+	User              = "user"
+	Password          = "password"
+	Port              = "port"
+	Retry             = "retry"
+	PoolMaxOpen       = "db_pool.max_open"
+	PoolMaxIdle       = "db_pool.max_idle"
+	PoolMaxLifetime   = "db_pool.conn_max_lifetime"
+	PoolHealthCheck   = "db_pool.health_check_interval"
+	PoolBackoffBase   = "db_pool.backoff_base"
+	PoolBackoffMax    = "db_pool.backoff_max"
+	BreakerThreshold  = "db_pool.breaker_threshold"
+	BreakerCooldown   = "db_pool.breaker_cooldown"
+	Database          = "database"
+	TimeFormat        = "Jan 02 2006 03:04PM"
+	MatDateTimeFomat  = "2006/01/02 15:04"
+	ExpFormat         = "02 Jan 2006"
+	LogTimeFormat     = "2006-01-02-15:04"
+	RunIDFormat       = "20060102-150405"
+	RunTimeFormat     = "2006-01-02 15:04:05"
+	SQL               = "sqlserver"
+	EQInsertQuery     = "eqDataInsertion"
+	DERInsertQuery    = "dervDataInsertion"
+	BackUpProcedure   = "backUpProc"
+	DeleteDerivative  = "deleteDervProc"
+	DeleteEquity      = "deleteEQProc"
+	MarketCapQuery    = "marketCapProc"
+	StockIDQuery      = "stockIDUpdate"
+	PrepareStaging    = "prepareStagingProc"
+	StagingEQProc     = "stagingEQProc"
+	StagingDervProc   = "stagingDervProc"
+	StagingCountQuery = "stagingCountQuery"
+	SwapStaging       = "swapStagingProc"
+	RestoreEquity     = "restoreEQProc"
+	RestoreDerivative = "restoreDervProc"
+	BackUpList        = "backUpListQuery"
+	BackUpInspect     = "backUpInspectQuery"
+	BackUpPrune       = "backUpPruneProc"
+	QuarantineProc    = "quarantineProc"
+	BulkDerivative    = "bulkDerivative"
+	BulkMerge         = "bulkMergeProc"
+	BulkMergeStaging  = "bulkMergeStagingProc"
+	MasterSnapshot    = "masterSnapshotQuery"
+	ExportQuery       = "exportQuery"
+	AssetEquity       = "Equity"
+	AssetDerivative   = "Derivative"
 )
 
 // log constants
@@ -46,14 +75,60 @@ const (
 
 // api constants
 const (
-	SegmentsAllowed  = "segments_allowed"
-	Env              = "env"
-	ContentType      = "application/json"
-	LastPage         = "hasLastPage"
-	NextPage         = "nextPage"
-	NseSeries        = "nse_series"
-	BseSeries        = "bse_series"
-	IndexInstruments = "index_instruments"
+	Segments             = "segments"
+	Env                  = "env"
+	ContentType          = "application/json"
+	LastPage             = "hasLastPage"
+	NextPage             = "nextPage"
+	Storage              = "storage"
+	StoragePath          = "storage_path"
+	StorageMSSQL         = "mssql"
+	StorageMemory        = "memory"
+	LoadMode             = "load_mode"
+	LoadModeDirect       = "direct"
+	LoadModeSwap         = "swap"
+	RollbackOnFailure    = "rollback_on_failure"
+	RetentionGenerations = "backup_retention_generations"
+	RetentionDays        = "backup_retention_days"
+	DiffReport           = "diff_report"
+	ReportPath           = "report_path"
+	DryRunPath           = "dry_run_path"
+	QuarantineMode       = "quarantine.mode"
+	BulkBatchSize        = "bulk_load.batch_size"
+	BulkParallelism      = "bulk_load.parallelism"
+	ExpiryGraceDays      = "expiry_grace_days"
+	PipelineFetchers     = "pipeline.fetchers"
+	PipelineParsers      = "pipeline.parsers"
+	PipelinePageBuffer   = "pipeline.page_buffer"
+	PipelineLoaders      = "pipeline.loaders"
+	QuarantinePath       = "quarantine.path"
+	ArchiveEnabled       = "archive.enabled"
+	GatesMinRows         = "gates.min_rows"
+	GatesMaxDrop         = "gates.max_drop_percent"
+	GatesMaxSkip         = "gates.max_skip_percent"
+	GatesUnderlyings     = "gates.required_underlyings"
+	ArchivePath          = "archive.path"
+	QuarantineOff        = "off"
+	QuarantineFile       = "file"
+	QuarantineTable      = "table"
+	ExportEnabled        = "export.enabled"
+	ExportPath           = "export.path"
+	ExportFormats        = "export.formats"
+	ExportPerSegment     = "export.per_segment"
+	ExportGzip           = "export.gzip"
+	ExportManifest       = "export.manifest"
+	BuildMarker          = "build_marker"
+	ServeAddress         = "serve.address"
+	ServePoll            = "serve.poll_interval"
+	ServePageSize        = "serve.page_size"
+	AMXTimeout           = "amx_client.timeout"
+	AMXMaxRetries        = "amx_client.max_retries"
+	AMXBackoffBase       = "amx_client.backoff_base"
+	AMXBackoffMax        = "amx_client.backoff_max"
+	MojoTimeout          = "mojo_client.timeout"
+	MojoMaxRetries       = "mojo_client.max_retries"
+	MojoBackoffBase      = "mojo_client.backoff_base"
+	MojoBackoffMax       = "mojo_client.backoff_max"
 )
 
 // config file path
@@ -61,6 +136,23 @@ const (
 	BaseConfigPathKey          = "base-config-path"
 	BaseConfigPathDefaultValue = "resources/configs"
 	BaseConfigPathUsage        = "path to folder that stores your configurations"
+	AssetClassKey              = "asset-class"
+	AssetClassDefaultValue     = "all"
+	AssetClassUsage            = "asset class to restore: equity, derivative or all"
+	BuildCommand               = "build"
+	RestoreCommand             = "restore"
+	BackUpsCommand             = "backups"
+	ExportCommand              = "export"
+	ServeCommand               = "serve"
+	RebuildCommand             = "rebuild"
+	FromArchiveKey             = "from-archive"
+	FromArchiveUsage           = "run id of the archived pages the rebuild command loads"
+	GenerationKey              = "generation"
+	GenerationUsage            = "backup run id to restore, latest when empty"
+	DryRunKey                  = "dry-run"
+	DryRunUsage                = "fetch and parse, write the procedure calls to dry_run_path instead of the database"
+	ForceKey                   = "force"
+	ForceUsage                 = "load even when a sanity gate trips"
 	GetSecinfoUrl              = "getSecInfo"
 	StockMasterUrl             = "stockMaster"
 	GetLoginUrl                = "amxLogin"
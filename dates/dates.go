@@ -0,0 +1,126 @@
+// Package dates converts the date fields of getAllSecInfo. They are
+// seconds since the epoch of the exchange segment, and a contract expires
+// at the end of its expiry day in exchange time.
+package dates
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	_ "time/tzdata" // the exchange zone does not depend on the host's zoneinfo
+)
+
+// Zone is the timezone of the Indian exchanges.
+const Zone = "Asia/Kolkata"
+
+// EpochLayout is the layout of a configured segment epoch.
+const EpochLayout = "2006-01-02"
+
+// Exchange is the location of Zone.
+var Exchange = mustLoad(Zone)
+
+// Unix is the epoch of segments without a configured one.
+var Unix = time.Unix(0, 0).UTC()
+
+// ParseEpoch reads a segment epoch, a date in EpochLayout taken as
+// midnight exchange time. An empty epoch is the Unix epoch.
+func ParseEpoch(value string) (time.Time, error) {
+
+	if value == "" {
+		return Unix, nil
+	}
+	epoch, err := time.ParseInLocation(EpochLayout, value, Exchange)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid epoch %q, expected %s", value, EpochLayout)
+	}
+	return epoch, nil
+}
+
+// Calendar knows the epoch of every segment. A contract is kept for
+// GraceDays days after its expiry day.
+type Calendar struct {
+	epochs    map[string]time.Time
+	GraceDays int
+}
+
+// New returns a calendar with the epochs per segment code, segments not
+// in epochs count from the Unix epoch.
+func New(epochs map[string]time.Time, graceDays int) *Calendar {
+
+	if epochs == nil {
+		epochs = make(map[string]time.Time)
+	}
+	return &Calendar{epochs: epochs, GraceDays: graceDays}
+}
+
+// Epoch returns the epoch of a segment.
+func (c *Calendar) Epoch(segment string) time.Time {
+
+	if epoch, ok := c.epochs[segment]; ok {
+		return epoch
+	}
+	return Unix
+}
+
+// Time converts a date field of a segment to exchange time. ok is false
+// for an empty field, err is set when the field is not a number.
+func (c *Calendar) Time(segment, value string) (t time.Time, ok bool, err error) {
+
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	return time.Unix(c.Epoch(segment).Unix()+seconds, 0).In(Exchange), true, nil
+}
+
+// Format formats a date field in exchange time, "-" when the field is
+// empty, zero or invalid.
+func (c *Calendar) Format(segment, value, layout string) string {
+
+	t, ok, err := c.Time(segment, value)
+	if !ok || err != nil || t.Equal(c.Epoch(segment)) {
+		return "-"
+	}
+	return t.Format(layout)
+}
+
+// ExpiresAt returns the moment a contract with the expiry field is no
+// longer kept: the end of its expiry day plus the grace days.
+func (c *Calendar) ExpiresAt(segment, value string) (time.Time, bool, error) {
+
+	t, ok, err := c.Time(segment, value)
+	if !ok || err != nil {
+		return time.Time{}, ok, err
+	}
+	return EndOfDay(t).AddDate(0, 0, c.GraceDays), true, nil
+}
+
+// Expired reports whether a contract has expired at now. An empty expiry
+// never expires and an invalid one always has.
+func (c *Calendar) Expired(segment, value string, now time.Time) bool {
+
+	end, ok, err := c.ExpiresAt(segment, value)
+	if err != nil {
+		return true
+	}
+	return ok && !now.Before(end)
+}
+
+// EndOfDay returns the midnight in exchange time that ends the day of t.
+func EndOfDay(t time.Time) time.Time {
+
+	y, m, d := t.In(Exchange).Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, Exchange)
+}
+
+func mustLoad(name string) *time.Location {
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
@@ -0,0 +1,138 @@
+package dates_test
+
+import (
+	"testing"
+	"time"
+
+	"main.go/dates"
+)
+
+// 27 Jun 2024 15:30 IST
+const (
+	nseExpiry  = "1403969400"
+	unixExpiry = "1719482400"
+)
+
+func calendar(t *testing.T, graceDays int) *dates.Calendar {
+
+	t.Helper()
+	epoch, err := dates.ParseEpoch("1980-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dates.New(map[string]time.Time{"nse_fo": epoch, "bse_cm": epoch}, graceDays)
+}
+
+func ist(value string) time.Time {
+
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, dates.Exchange)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseEpoch(t *testing.T) {
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "", want: dates.Unix},
+		{value: "1980-01-01", want: time.Unix(315513000, 0)},
+		{value: "01-01-1980", wantErr: true},
+		{value: "unix", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := dates.ParseEpoch(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEpoch(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !got.Equal(tt.want) {
+			t.Errorf("ParseEpoch(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+
+	c := calendar(t, 0)
+	tests := []struct {
+		name    string
+		segment string
+		value   string
+		layout  string
+		want    string
+	}{
+		{"nse epoch", "nse_fo", nseExpiry, "02 Jan 2006 15:04", "27 Jun 2024 15:30"},
+		{"bse epoch", "bse_cm", nseExpiry, "02 Jan 2006 15:04", "27 Jun 2024 15:30"},
+		{"unix epoch", "mcx_fo", unixExpiry, "02 Jan 2006 15:04", "27 Jun 2024 15:30"},
+		{"unix seconds on an nse segment", "nse_fo", unixExpiry, "02 Jan 2006", "27 Jun 2034"},
+		{"late evening stays on its ist day", "mcx_fo", "1719511200", "02 Jan 2006 15:04", "27 Jun 2024 23:30"},
+		{"epoch itself", "nse_fo", "0", "02 Jan 2006", "-"},
+		{"empty", "nse_fo", "", "02 Jan 2006", "-"},
+		{"invalid", "nse_fo", "27-06-2024", "02 Jan 2006", "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Format(tt.segment, tt.value, tt.layout); got != tt.want {
+				t.Errorf("Format(%q, %q) = %q, want %q", tt.segment, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpired(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		segment   string
+		value     string
+		graceDays int
+		now       time.Time
+		want      bool
+	}{
+		{"before expiry", "nse_fo", nseExpiry, 0, ist("2024-06-26 10:00:00"), false},
+		{"expiry day after the close", "nse_fo", nseExpiry, 0, ist("2024-06-27 20:00:00"), false},
+		{"last second of expiry day", "nse_fo", nseExpiry, 0, ist("2024-06-27 23:59:59"), false},
+		{"next ist day", "nse_fo", nseExpiry, 0, ist("2024-06-28 00:00:00"), true},
+		{"next ist day, still expiry day in utc", "nse_fo", nseExpiry, 0, time.Date(2024, 6, 27, 19, 0, 0, 0, time.UTC), true},
+		{"unix epoch segment", "mcx_fo", unixExpiry, 0, ist("2024-06-27 20:00:00"), false},
+		{"unix epoch segment next day", "mcx_fo", unixExpiry, 0, ist("2024-06-28 09:00:00"), true},
+		{"within grace days", "nse_fo", nseExpiry, 2, ist("2024-06-29 23:00:00"), false},
+		{"after grace days", "nse_fo", nseExpiry, 2, ist("2024-06-30 00:00:00"), true},
+		{"empty never expires", "nse_fo", "", 0, ist("2024-06-28 00:00:00"), false},
+		{"invalid always has", "nse_fo", "soon", 0, ist("1990-01-01 00:00:00"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calendar(t, tt.graceDays).Expired(tt.segment, tt.value, tt.now); got != tt.want {
+				t.Errorf("Expired(%q, %q) at %v = %v, want %v", tt.segment, tt.value, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndOfDay(t *testing.T) {
+
+	tests := []struct {
+		in   time.Time
+		want time.Time
+	}{
+		{ist("2024-06-27 00:00:00"), ist("2024-06-28 00:00:00")},
+		{ist("2024-06-27 15:30:00"), ist("2024-06-28 00:00:00")},
+		{ist("2024-12-31 23:59:59"), ist("2025-01-01 00:00:00")},
+		{time.Date(2024, 6, 27, 20, 0, 0, 0, time.UTC), ist("2024-06-29 00:00:00")},
+	}
+
+	for _, tt := range tests {
+		if got := dates.EndOfDay(tt.in); !got.Equal(tt.want) {
+			t.Errorf("EndOfDay(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package decimal is an exact fixed-point number type for the prices,
+// strikes, tick sizes and freeze percentages of the scrip master.
+package decimal
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrDivisionByZero is returned when dividing by zero.
+var ErrDivisionByZero = errors.New("decimal: division by zero")
+
+var ten = big.NewInt(10)
+
+// maxExponent bounds the exponent and the scale Parse accepts, so a number
+// from an untrusted source cannot make it allocate without limit.
+const maxExponent = 30
+
+// Decimal is unscaled * 10^-scale. The zero value is 0, and a Decimal is
+// never changed once made, so it can be copied freely.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// New returns unscaled * 10^-scale.
+func New(unscaled int64, scale int) Decimal {
+
+	if scale < 0 {
+		return Decimal{unscaled: new(big.Int).Mul(big.NewInt(unscaled), pow10(-scale))}
+	}
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// Parse reads a decimal number such as "-12.50" or "1.5e3". Numbers with an
+// exponent or a scale beyond maxExponent are rejected.
+func Parse(value string) (Decimal, error) {
+
+	s := strings.TrimSpace(value)
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		exp, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("decimal: invalid number %q", value)
+		}
+		if exp > maxExponent || exp < -maxExponent {
+			return Decimal{}, fmt.Errorf("decimal: exponent of %q out of range", value)
+		}
+		mantissa, exponent = s[:i], exp
+	}
+
+	digits := mantissa
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		digits = digits[1:]
+	}
+	whole, fraction := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, fraction = digits[:i], digits[i+1:]
+	}
+	if whole+fraction == "" || strings.Trim(whole+fraction, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("decimal: invalid number %q", value)
+	}
+
+	scale := len(fraction) - exponent
+	if scale > maxExponent || scale < -maxExponent {
+		return Decimal{}, fmt.Errorf("decimal: scale of %q out of range", value)
+	}
+
+	unscaled, _ := new(big.Int).SetString(whole+fraction, 10)
+	if strings.HasPrefix(mantissa, "-") {
+		unscaled.Neg(unscaled)
+	}
+
+	if scale < 0 {
+		return Decimal{unscaled: unscaled.Mul(unscaled, pow10(-scale))}, nil
+	}
+	return Decimal{unscaled: unscaled, scale: scale}, nil
+}
+
+// Scale returns the number of decimals of d.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign returns -1, 0 or +1.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and o by value, whatever their scale.
+func (d Decimal) Cmp(o Decimal) int {
+
+	a, b := align(d, o)
+	return a.Cmp(b)
+}
+
+func (d Decimal) Add(o Decimal) Decimal {
+
+	a, b := align(d, o)
+	return Decimal{unscaled: a.Add(a, b), scale: max(d.scale, o.scale)}
+}
+
+func (d Decimal) Sub(o Decimal) Decimal {
+
+	a, b := align(d, o)
+	return Decimal{unscaled: a.Sub(a, b), scale: max(d.scale, o.scale)}
+}
+
+// Mul returns the exact product, its scale is the sum of both scales.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{unscaled: new(big.Int).Mul(d.int(), o.int()), scale: d.scale + o.scale}
+}
+
+// Quo returns d / o rounded half away from zero to scale decimals.
+func (d Decimal) Quo(o Decimal, scale int) (Decimal, error) {
+
+	if o.IsZero() {
+		return Decimal{}, ErrDivisionByZero
+	}
+	if scale < 0 {
+		scale = 0
+	}
+
+	// d / o = d.unscaled * 10^(scale - d.scale + o.scale) / o.unscaled * 10^-scale
+	num, den := new(big.Int).Set(d.int()), new(big.Int).Set(o.int())
+	if shift := scale - d.scale + o.scale; shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	return Decimal{unscaled: roundQuo(num, den), scale: scale}, nil
+}
+
+// Div returns d / divisor rounded half away from zero to scale decimals.
+func (d Decimal) Div(divisor int64, scale int) (Decimal, error) {
+	return d.Quo(New(divisor, 0), scale)
+}
+
+// Round returns d rounded half away from zero to scale decimals, padded
+// with zeros when d has fewer.
+func (d Decimal) Round(scale int) Decimal {
+
+	if scale < 0 {
+		scale = 0
+	}
+	if scale >= d.scale {
+		return Decimal{unscaled: new(big.Int).Mul(d.int(), pow10(scale-d.scale)), scale: scale}
+	}
+	return Decimal{unscaled: roundQuo(d.int(), pow10(d.scale-scale)), scale: scale}
+}
+
+// Int64 returns the integer part of d and whether d is an integer that
+// fits an int64.
+func (d Decimal) Int64() (int64, bool) {
+
+	q, r := new(big.Int).QuoRem(d.int(), pow10(d.scale), new(big.Int))
+	return q.Int64(), r.Sign() == 0 && q.IsInt64()
+}
+
+// String formats d with all of its decimals.
+func (d Decimal) String() string {
+
+	digits := new(big.Int).Abs(d.int()).String()
+	if d.scale > 0 {
+		if pad := d.scale + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if d.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// StringFixed formats d rounded to scale decimals.
+func (d Decimal) StringFixed(scale int) string {
+	return d.Round(scale).String()
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Decimal) int() *big.Int {
+
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return d.unscaled
+}
+
+// align returns the unscaled values of a and b at the larger scale.
+func align(a, b Decimal) (*big.Int, *big.Int) {
+
+	x, y := new(big.Int).Set(a.int()), new(big.Int).Set(b.int())
+	if a.scale < b.scale {
+		x.Mul(x, pow10(b.scale-a.scale))
+	} else if b.scale < a.scale {
+		y.Mul(y, pow10(a.scale-b.scale))
+	}
+	return x, y
+}
+
+// roundQuo returns num / den rounded half away from zero.
+func roundQuo(num, den *big.Int) *big.Int {
+
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	twice := new(big.Int).Abs(r)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(new(big.Int).Abs(den)) >= 0 {
+		if num.Sign()*den.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(ten, big.NewInt(int64(n)), nil)
+}
+
+func max(a, b int) int {
+
+	if a > b {
+		return a
+	}
+	return b
+}
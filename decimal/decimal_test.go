@@ -0,0 +1,192 @@
+package decimal_test
+
+import (
+	"testing"
+
+	"main.go/decimal"
+)
+
+func parse(t *testing.T, value string) decimal.Decimal {
+
+	t.Helper()
+	d, err := decimal.Parse(value)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", value, err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "0", want: "0"},
+		{value: "1250", want: "1250"},
+		{value: "-12.50", want: "-12.50"},
+		{value: "+0.05", want: "0.05"},
+		{value: ".5", want: "0.5"},
+		{value: "7.", want: "7"},
+		{value: " 42 ", want: "42"},
+		{value: "1.5e3", want: "1500"},
+		{value: "125E-4", want: "0.0125"},
+		{value: "92233720368547758070.5", want: "92233720368547758070.5"},
+		{value: "", wantErr: true},
+		{value: "-", wantErr: true},
+		{value: "1.2.3", wantErr: true},
+		{value: "12a", wantErr: true},
+		{value: "1e", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "1e30", want: "1000000000000000000000000000000"},
+		{value: "1e-30", want: "0.000000000000000000000000000001"},
+		{value: "1e31", wantErr: true},
+		{value: "1e-31", wantErr: true},
+		{value: "1e999999999", wantErr: true},
+		{value: "1e-999999999", wantErr: true},
+		{value: "0.0000000000000000000000000000001", wantErr: true},
+		{value: "0.5e-30", wantErr: true},
+		{value: "1e99999999999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := decimal.Parse(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDiv(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		value   string
+		divisor int64
+		scale   int
+		want    string
+	}{
+		{"paise to rupees", "245075", 100, 2, "2450.75"},
+		{"currency strike", "8325000", 10000000, 4, "0.8325"},
+		{"large currency strike", "16777217000000", 10000000, 4, "1677721.7000"},
+		{"pads to scale", "5", 100, 4, "0.0500"},
+		{"fractional input", "12345.5", 100, 2, "123.46"},
+		{"rounds half away from zero", "125", 1000, 2, "0.13"},
+		{"rounds negative half away from zero", "-125", 1000, 2, "-0.13"},
+		{"rounds down below half", "124", 1000, 2, "0.12"},
+		{"non power of ten", "10", 3, 4, "3.3333"},
+		{"zero", "0", 100, 2, "0.00"},
+		{"zero scale", "250", 100, 0, "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(t, tt.value).Div(tt.divisor, tt.scale)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("%s / %d = %s, want %s", tt.value, tt.divisor, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parse(t, "1").Div(0, 2); err != decimal.ErrDivisionByZero {
+		t.Errorf("division by zero: got %v", err)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+
+	tests := []struct {
+		a, b                 string
+		sum, diff, prod, quo string
+		cmp                  int
+	}{
+		{"1.5", "0.25", "1.75", "1.25", "0.375", "6.0000", 1},
+		{"10", "10.00", "20.00", "0.00", "100.00", "1.0000", 0},
+		{"-2", "3", "1", "-5", "-6", "-0.6667", -1},
+		{"0.1", "0.2", "0.3", "-0.1", "0.02", "0.5000", -1},
+	}
+
+	for _, tt := range tests {
+		a, b := parse(t, tt.a), parse(t, tt.b)
+		if got := a.Add(b).String(); got != tt.sum {
+			t.Errorf("%s + %s = %s, want %s", tt.a, tt.b, got, tt.sum)
+		}
+		if got := a.Sub(b).String(); got != tt.diff {
+			t.Errorf("%s - %s = %s, want %s", tt.a, tt.b, got, tt.diff)
+		}
+		if got := a.Mul(b).String(); got != tt.prod {
+			t.Errorf("%s * %s = %s, want %s", tt.a, tt.b, got, tt.prod)
+		}
+		quo, err := a.Quo(b, 4)
+		if err != nil || quo.String() != tt.quo {
+			t.Errorf("%s / %s = %s (%v), want %s", tt.a, tt.b, quo, err, tt.quo)
+		}
+		if got := a.Cmp(b); got != tt.cmp {
+			t.Errorf("Cmp(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.cmp)
+		}
+	}
+}
+
+func TestStringFixed(t *testing.T) {
+
+	tests := []struct {
+		value string
+		scale int
+		want  string
+	}{
+		{"2450.755", 2, "2450.76"},
+		{"2450.745", 2, "2450.75"},
+		{"-0.005", 2, "-0.01"},
+		{"0.004", 2, "0.00"},
+		{"3", 2, "3.00"},
+		{"99.95", 1, "100.0"},
+		{"1.5", -1, "2"},
+	}
+
+	for _, tt := range tests {
+		if got := parse(t, tt.value).StringFixed(tt.scale); got != tt.want {
+			t.Errorf("StringFixed(%s, %d) = %s, want %s", tt.value, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestInt64(t *testing.T) {
+
+	tests := []struct {
+		value  string
+		want   int64
+		wantOK bool
+	}{
+		{"42", 42, true},
+		{"42.00", 42, true},
+		{"-7", -7, true},
+		{"42.5", 42, false},
+		{"92233720368547758070", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parse(t, tt.value).Int64()
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("Int64(%s) = %d, %v, want %d, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+
+	var d decimal.Decimal
+	if d.String() != "0" || !d.IsZero() || d.StringFixed(2) != "0.00" {
+		t.Errorf("zero value formats as %q / %q", d.String(), d.StringFixed(2))
+	}
+	if got := d.Add(decimal.New(5, 1)).String(); got != "0.5" {
+		t.Errorf("0 + 0.5 = %s", got)
+	}
+}
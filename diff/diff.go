@@ -0,0 +1,195 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Record is the part of a scrip master row that the diff compares, keyed on
+// nTokenMktID (token and market segment id).
+type Record struct {
+	TokenMktID string `json:"nTokenMktID"`
+	Segment    string `json:"segment"`
+	Symbol     string `json:"symbol"`
+	LotSize    string `json:"lotSize"`
+	TickSize   string `json:"tickSize"`
+	FreezeQty  string `json:"freezeQty"`
+	ISIN       string `json:"isin"`
+}
+
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type Modified struct {
+	TokenMktID string   `json:"nTokenMktID"`
+	Symbol     string   `json:"symbol"`
+	Changes    []Change `json:"changes"`
+}
+
+type SegmentReport struct {
+	Segment   string     `json:"segment"`
+	Previous  int        `json:"previous"`
+	Current   int        `json:"current"`
+	Unchanged int        `json:"unchanged"`
+	Added     []Record   `json:"added"`
+	Removed   []Record   `json:"removed"`
+	Modified  []Modified `json:"modified"`
+}
+
+type Report struct {
+	RunID       string          `json:"runId"`
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Segments    []SegmentReport `json:"segments"`
+}
+
+// Compare builds the report between the previous and the newly built master.
+func Compare(runID string, previous, current []Record) Report {
+
+	prev := index(previous)
+	curr := index(current)
+	segments := make(map[string]*SegmentReport)
+
+	segmentOf := func(name string) *SegmentReport {
+		if seg, ok := segments[name]; ok {
+			return seg
+		}
+		seg := &SegmentReport{Segment: name}
+		segments[name] = seg
+		return seg
+	}
+
+	for key, old := range prev {
+		seg := segmentOf(old.Segment)
+		seg.Previous++
+
+		now, ok := curr[key]
+		if !ok {
+			seg.Removed = append(seg.Removed, old)
+			continue
+		}
+		if changes := compareFields(old, now); len(changes) > 0 {
+			seg.Modified = append(seg.Modified, Modified{TokenMktID: key, Symbol: now.Symbol, Changes: changes})
+		} else {
+			seg.Unchanged++
+		}
+	}
+
+	for key, now := range curr {
+		seg := segmentOf(now.Segment)
+		seg.Current++
+		if _, ok := prev[key]; !ok {
+			seg.Added = append(seg.Added, now)
+		}
+	}
+
+	report := Report{RunID: runID, GeneratedAt: time.Now()}
+	for _, seg := range segments {
+		sort.Slice(seg.Added, func(i, j int) bool { return seg.Added[i].TokenMktID < seg.Added[j].TokenMktID })
+		sort.Slice(seg.Removed, func(i, j int) bool { return seg.Removed[i].TokenMktID < seg.Removed[j].TokenMktID })
+		sort.Slice(seg.Modified, func(i, j int) bool { return seg.Modified[i].TokenMktID < seg.Modified[j].TokenMktID })
+		report.Segments = append(report.Segments, *seg)
+	}
+	sort.Slice(report.Segments, func(i, j int) bool { return report.Segments[i].Segment < report.Segments[j].Segment })
+
+	return report
+}
+
+func index(records []Record) map[string]Record {
+
+	indexed := make(map[string]Record, len(records))
+	for _, r := range records {
+		indexed[r.TokenMktID] = r
+	}
+	return indexed
+}
+
+func compareFields(old, now Record) []Change {
+
+	var changes []Change
+	fields := []struct {
+		name     string
+		old, now string
+		numeric  bool
+	}{
+		{"lotSize", old.LotSize, now.LotSize, true},
+		{"tickSize", old.TickSize, now.TickSize, true},
+		{"freezeQty", old.FreezeQty, now.FreezeQty, true},
+		{"isin", old.ISIN, now.ISIN, false},
+		{"symbol", old.Symbol, now.Symbol, false},
+	}
+	for _, f := range fields {
+		if f.old == f.now || (f.numeric && numericEqual(f.old, f.now)) {
+			continue
+		}
+		changes = append(changes, Change{Field: f.name, Old: f.old, New: f.now})
+	}
+	return changes
+}
+
+// numericEqual treats "5", "5.00" and "5.0000" as the same value, since the
+// database returns decimals with their column scale.
+func numericEqual(a, b string) bool {
+
+	x, okA := new(big.Rat).SetString(a)
+	y, okB := new(big.Rat).SetString(b)
+	return okA && okB && x.Cmp(y) == 0
+}
+
+// Write stores the report as diff.json and diff.txt in dir.
+func (r Report) Write(dir string) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "diff.json"), data, 0644); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(dir, "diff.txt"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r.WriteSummary(f)
+	return f.Close()
+}
+
+// WriteSummary writes the human readable summary of the report.
+func (r Report) WriteSummary(w io.Writer) {
+
+	fmt.Fprintf(w, "Scrip master diff for run %s (%s)\n", r.RunID, r.GeneratedAt.Format("2006-01-02 15:04:05"))
+
+	for _, seg := range r.Segments {
+		fmt.Fprintf(w, "\n[%s] previous %d, current %d: %d added, %d removed, %d modified, %d unchanged\n",
+			seg.Segment, seg.Previous, seg.Current, len(seg.Added), len(seg.Removed), len(seg.Modified), seg.Unchanged)
+
+		for _, a := range seg.Added {
+			fmt.Fprintf(w, "  + %s %s\n", a.TokenMktID, a.Symbol)
+		}
+		for _, d := range seg.Removed {
+			fmt.Fprintf(w, "  - %s %s\n", d.TokenMktID, d.Symbol)
+		}
+		for _, m := range seg.Modified {
+			fmt.Fprintf(w, "  ~ %s %s", m.TokenMktID, m.Symbol)
+			for _, c := range m.Changes {
+				fmt.Fprintf(w, " %s: %s -> %s;", c.Field, c.Old, c.New)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
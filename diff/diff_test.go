@@ -0,0 +1,112 @@
+package diff_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"main.go/diff"
+)
+
+func TestCompare(t *testing.T) {
+
+	previous := []diff.Record{
+		{TokenMktID: "35001_2", Segment: "nse_fo", Symbol: "NIFTY", LotSize: "75", TickSize: "0.05", FreezeQty: "1800"},
+		{TokenMktID: "35002_2", Segment: "nse_fo", Symbol: "BANKNIFTY", LotSize: "30", TickSize: "0.05", FreezeQty: "900"},
+		{TokenMktID: "35003_2", Segment: "nse_fo", Symbol: "NIFTY", LotSize: "75", TickSize: "0.05"},
+		{TokenMktID: "2885_1", Segment: "nse_cm", Symbol: "RELIANCE", LotSize: "1", TickSize: "0.0500", ISIN: "INE002A01018"},
+	}
+	current := []diff.Record{
+		// lot size changed
+		{TokenMktID: "35001_2", Segment: "nse_fo", Symbol: "NIFTY", LotSize: "50", TickSize: "0.05", FreezeQty: "1800"},
+		// unchanged, listed in another order
+		{TokenMktID: "35003_2", Segment: "nse_fo", Symbol: "NIFTY", LotSize: "75", TickSize: "0.05"},
+		{TokenMktID: "35009_2", Segment: "nse_fo", Symbol: "FINNIFTY", LotSize: "40", TickSize: "0.05"},
+		// the same tick at another scale, the isin changed
+		{TokenMktID: "2885_1", Segment: "nse_cm", Symbol: "RELIANCE", LotSize: "1", TickSize: "0.05", ISIN: "INE002A01026"},
+		// the same token on another market segment is another scrip
+		{TokenMktID: "2885_3", Segment: "bse_cm", Symbol: "RELIANCE", LotSize: "1", TickSize: "0.05"},
+	}
+
+	report := diff.Compare("run-1", previous, current)
+	if report.RunID != "run-1" {
+		t.Errorf("RunID %q", report.RunID)
+	}
+
+	want := []diff.SegmentReport{
+		{Segment: "bse_cm", Previous: 0, Current: 1, Added: []diff.Record{current[4]}},
+		{Segment: "nse_cm", Previous: 1, Current: 1, Modified: []diff.Modified{
+			{TokenMktID: "2885_1", Symbol: "RELIANCE", Changes: []diff.Change{{Field: "isin", Old: "INE002A01018", New: "INE002A01026"}}},
+		}},
+		{Segment: "nse_fo", Previous: 3, Current: 3, Unchanged: 1,
+			Added:   []diff.Record{current[2]},
+			Removed: []diff.Record{previous[1]},
+			Modified: []diff.Modified{
+				{TokenMktID: "35001_2", Symbol: "NIFTY", Changes: []diff.Change{{Field: "lotSize", Old: "75", New: "50"}}},
+			}},
+	}
+	if !reflect.DeepEqual(report.Segments, want) {
+		t.Errorf("Compare() =\n%+v\nwant\n%+v", report.Segments, want)
+	}
+}
+
+func TestCompareSortsByToken(t *testing.T) {
+
+	var current []diff.Record
+	for _, token := range []string{"35005_2", "35001_2", "35003_2"} {
+		current = append(current, diff.Record{TokenMktID: token, Segment: "nse_fo"})
+	}
+
+	report := diff.Compare("run-1", nil, current)
+	var added []string
+	for _, r := range report.Segments[0].Added {
+		added = append(added, r.TokenMktID)
+	}
+	if want := []string{"35001_2", "35003_2", "35005_2"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("added %v, want %v", added, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+
+	previous := []diff.Record{{TokenMktID: "35002_2", Segment: "nse_fo", Symbol: "BANKNIFTY", LotSize: "30"}}
+	current := []diff.Record{
+		{TokenMktID: "35002_2", Segment: "nse_fo", Symbol: "BANKNIFTY", LotSize: "35"},
+		{TokenMktID: "35009_2", Segment: "nse_fo", Symbol: "FINNIFTY"},
+	}
+	report := diff.Compare("run-1", previous, current)
+
+	dir := t.TempDir()
+	if err := report.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "diff.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read diff.Report
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Segments, report.Segments) {
+		t.Errorf("diff.json segments %+v, want %+v", read.Segments, report.Segments)
+	}
+
+	text, err := os.ReadFile(filepath.Join(dir, "diff.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"[nse_fo] previous 1, current 2: 1 added, 0 removed, 1 modified, 0 unchanged",
+		"  + 35009_2 FINNIFTY",
+		"  ~ 35002_2 BANKNIFTY lotSize: 30 -> 35;",
+	} {
+		if !strings.Contains(string(text), line+"\n") {
+			t.Errorf("diff.txt misses %q:\n%s", line, text)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package entities
+
+import "main.go/decimal"
+
+// Scrip is a single record of the AMX getAllSecInfo response.
+type Scrip struct {
+	Symbol            string `json:"symbol"`
+	SymbolName        string `json:"symbolName"`
+	TrdSymbol         string `json:"trdSymbol"`
+	Series            string `json:"series"`
+	InstrumentType    string `json:"instrumentType"`
+	MarketSegmentID   string `json:"marketSegmentId"`
+	MarketType        string `json:"marketType"`
+	RemarksText       string `json:"remarksText"`
+	SecurityDesc      string `json:"securityDesc"`
+	IsinCode          string `json:"isinCode"`
+	AssetToken        string `json:"assetToken"`
+	ExpiryDate        string `json:"expiryDate"`
+	OptionType        string `json:"optionType"`
+	FaceValue         string `json:"faceValue"`
+	QtyUnits          string `json:"qtyUnits"`
+	DeliveryUnit      string `json:"deliveryUnit"`
+	PriceQuotFactor   string `json:"priceQuotFactor"`
+	IssueMaturityDate string `json:"issueMaturityDate"`
+	IssueStartDate    string `json:"issueStartDate"`
+
+	PriceTick           decimal.Decimal `json:"priceTick"`
+	MinimumLot          int64           `json:"minimumLot"`
+	RegularLot          int64           `json:"regularLot"`
+	LowPriceRange       decimal.Decimal `json:"lowPriceRange"`
+	HighPriceRange      decimal.Decimal `json:"highPriceRange"`
+	StrikePrice         decimal.Decimal `json:"strikePrice"`
+	PriceQuotUnit       int64           `json:"priceQuotUnit"`
+	MaxSingleTransQty   int64           `json:"maxSingleTransQty"`
+	MaxSingleTransValue int64           `json:"maxSingleTransValue"`
+	OpenInterest        int64           `json:"openInterest"`
+	TotalValueTraded    int64           `json:"totalValueTraded"`
+	FreezePercent       decimal.Decimal `json:"freezePercent"`
+	BasePrice           decimal.Decimal `json:"basePrice"`
+	IssueCapital        int64           `json:"issueCapital"`
+	NormalMarketAllowed int64           `json:"normalMarketAllowed"`
+	GenNum              int64           `json:"genNum"`
+	GenDen              int64           `json:"genDen"`
+	PriceNum            int64           `json:"priceNum"`
+	PriceDen            int64           `json:"priceDen"`
+}
+
+// EquityScrip is a cash segment scrip together with the values derived for the master.
+type EquityScrip struct {
+	Scrip
+	TokenMktID string
+	SegmentID  string
+	Divider    string
+	Precision  string
+	AssetClass string
+	ExpDate    string
+	Maturity   string
+	Details    string
+}
+
+// DerivativeScrip is a derivative contract together with the values derived for the master.
+type DerivativeScrip struct {
+	Scrip
+	TokenMktID string
+	SegmentID  string
+	Divider    string
+	Precision  string
+	AssetClass string
+	ExpDate    string
+	Maturity   string
+	Details    string
+	PriceNumer string
+	PriceDenom string
+}
@@ -0,0 +1,183 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"main.go/decimal"
+)
+
+// FieldError describes a single field of a record that could not be decoded.
+type FieldError struct {
+	Field  string
+	Value  interface{}
+	Reason string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s (%v)", e.Field, e.Reason, e.Value)
+}
+
+// DecodeError collects every field error found while decoding a record.
+type DecodeError struct {
+	Fields []FieldError
+}
+
+func (e *DecodeError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Error())
+	}
+	return "invalid scrip record: " + strings.Join(msgs, "; ")
+}
+
+// DecodeScrip converts a raw getAllSecInfo record into a Scrip. Strings and
+// numbers are accepted interchangeably and missing or null fields decode to
+// their zero value. Fields that still cannot be decoded are reported in a
+// *DecodeError, while the remaining fields are filled in as usual.
+func DecodeScrip(raw map[string]interface{}) (Scrip, error) {
+
+	d := &scripDecoder{raw: raw}
+	s := Scrip{
+		Symbol:            d.str("symbol"),
+		SymbolName:        d.str("symbolName"),
+		TrdSymbol:         d.str("trdSymbol"),
+		Series:            d.str("series"),
+		InstrumentType:    d.str("instrumentType"),
+		MarketSegmentID:   d.str("marketSegmentId"),
+		MarketType:        d.str("marketType"),
+		RemarksText:       d.str("remarksText"),
+		SecurityDesc:      d.str("securityDesc"),
+		IsinCode:          d.str("isinCode"),
+		AssetToken:        d.str("assetToken"),
+		ExpiryDate:        d.str("expiryDate"),
+		OptionType:        d.str("optionType"),
+		FaceValue:         d.str("faceValue"),
+		QtyUnits:          d.str("qtyUnits"),
+		DeliveryUnit:      d.str("deliveryUnit"),
+		PriceQuotFactor:   d.str("priceQuotFactor"),
+		IssueMaturityDate: d.str("issueMaturityDate"),
+		IssueStartDate:    d.str("issueStartDate"),
+
+		PriceTick:           d.decimal("priceTick"),
+		MinimumLot:          d.int("minimumLot"),
+		RegularLot:          d.int("regularLot"),
+		LowPriceRange:       d.decimal("lowPriceRange"),
+		HighPriceRange:      d.decimal("highPriceRange"),
+		StrikePrice:         d.decimal("strikePrice"),
+		PriceQuotUnit:       d.int("priceQuotUnit"),
+		MaxSingleTransQty:   d.int("maxSingleTransQty"),
+		MaxSingleTransValue: d.int("maxSingleTransValue"),
+		OpenInterest:        d.int("openInterest"),
+		TotalValueTraded:    d.int("totalValueTraded"),
+		FreezePercent:       d.decimal("freezePercent"),
+		BasePrice:           d.decimal("basePrice"),
+		IssueCapital:        d.int("issueCapital"),
+		NormalMarketAllowed: d.int("normalMarketAllowed"),
+		GenNum:              d.int("genNum"),
+		GenDen:              d.int("genDen"),
+		PriceNum:            d.int("priceNum"),
+		PriceDen:            d.int("priceDen"),
+	}
+
+	if len(d.errs) > 0 {
+		return s, &DecodeError{Fields: d.errs}
+	}
+	return s, nil
+}
+
+type scripDecoder struct {
+	raw  map[string]interface{}
+	errs []FieldError
+}
+
+func (d *scripDecoder) fail(field string, value interface{}, reason string) {
+	d.errs = append(d.errs, FieldError{Field: field, Value: value, Reason: reason})
+}
+
+func (d *scripDecoder) str(field string) string {
+
+	switch v := d.raw[field].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		d.fail(field, v, "expected string")
+		return ""
+	}
+}
+
+// int reads an integer exactly. A number with a fraction, such as "1.5",
+// or one beyond int64 is not an integer, "100.0" and "1e3" are.
+func (d *scripDecoder) int(field string) int64 {
+
+	var s string
+	switch v := d.raw[field].(type) {
+	case nil:
+		return 0
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		s = v.String()
+	case string:
+		s = strings.TrimSpace(v)
+		if s == "" || strings.EqualFold(s, "null") {
+			return 0
+		}
+	default:
+		d.fail(field, v, "expected number")
+		return 0
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	value, err := decimal.Parse(s)
+	if err != nil {
+		d.fail(field, d.raw[field], "invalid number")
+		return 0
+	}
+	n, ok := value.Int64()
+	if !ok {
+		d.fail(field, d.raw[field], "expected integer")
+		return 0
+	}
+	return n
+}
+
+func (d *scripDecoder) decimal(field string) decimal.Decimal {
+
+	var s string
+	switch v := d.raw[field].(type) {
+	case nil:
+		return decimal.Decimal{}
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		s = v.String()
+	case string:
+		s = strings.TrimSpace(v)
+		if s == "" || strings.EqualFold(s, "null") {
+			return decimal.Decimal{}
+		}
+	default:
+		d.fail(field, v, "expected number")
+		return decimal.Decimal{}
+	}
+
+	value, err := decimal.Parse(s)
+	if err != nil {
+		d.fail(field, d.raw[field], "invalid number")
+		return decimal.Decimal{}
+	}
+	return value
+}
@@ -0,0 +1,121 @@
+package entities_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"main.go/entities"
+)
+
+func TestDecodeScripIntegers(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		want    int64
+		wantErr string
+	}{
+		{name: "number", value: json.Number("75"), want: 75},
+		{name: "negative", value: json.Number("-3"), want: -3},
+		{name: "beyond float precision", value: json.Number("9007199254740993"), want: 9007199254740993},
+		{name: "max int64", value: json.Number("9223372036854775807"), want: 9223372036854775807},
+		{name: "integral fraction", value: json.Number("100.0"), want: 100},
+		{name: "exponent", value: json.Number("1e3"), want: 1000},
+		{name: "string", value: " 500 ", want: 500},
+		{name: "empty string", value: "", want: 0},
+		{name: "null string", value: "null", want: 0},
+		{name: "missing", value: nil, want: 0},
+		{name: "float64", value: float64(180000), want: 180000},
+		{name: "fraction", value: json.Number("1.5"), wantErr: "expected integer"},
+		{name: "fraction string", value: "0.25", wantErr: "expected integer"},
+		{name: "fraction float64", value: 2.5, wantErr: "expected integer"},
+		{name: "above int64", value: json.Number("9223372036854775808"), wantErr: "expected integer"},
+		{name: "below int64", value: json.Number("-9223372036854775809"), wantErr: "expected integer"},
+		{name: "float64 above int64", value: float64(1 << 63), wantErr: "expected integer"},
+		{name: "huge exponent", value: json.Number("1e400"), wantErr: "invalid number"},
+		{name: "not a number", value: "lots", wantErr: "invalid number"},
+		{name: "bool", value: true, wantErr: "expected number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := entities.DecodeScrip(map[string]interface{}{"symbol": "35001", "minimumLot": tt.value})
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s.MinimumLot != tt.want {
+					t.Errorf("MinimumLot = %d, want %d", s.MinimumLot, tt.want)
+				}
+				return
+			}
+
+			var decodeErr *entities.DecodeError
+			if !errors.As(err, &decodeErr) || len(decodeErr.Fields) != 1 {
+				t.Fatalf("error %v, want one field error", err)
+			}
+			if f := decodeErr.Fields[0]; f.Field != "minimumLot" || f.Reason != tt.wantErr {
+				t.Errorf("field error %v, want minimumLot: %s", f, tt.wantErr)
+			}
+			if s.MinimumLot != 0 || s.Symbol != "35001" {
+				t.Errorf("MinimumLot %d, Symbol %q, want the other fields decoded", s.MinimumLot, s.Symbol)
+			}
+		})
+	}
+}
+
+func TestDecodeScripRecord(t *testing.T) {
+
+	record := `{"symbol": 35003, "symbolName": "NIFTY", "instrumentType": "OPTIDX", "expiryDate": "1419859800",
+		"strikePrice": 2400000.5, "priceTick": "5", "minimumLot": "75", "regularLot": 75, "openInterest": 12345678901234567,
+		"genNum": null, "isinCode": null, "priceQuotFactor": 1}`
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(record)))
+	dec.UseNumber()
+	var raw map[string]interface{}
+	if err := dec.Decode(&raw); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := entities.DecodeScrip(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Symbol != "35003" || s.PriceQuotFactor != "1" || s.IsinCode != "" {
+		t.Errorf("strings %q %q %q", s.Symbol, s.PriceQuotFactor, s.IsinCode)
+	}
+	if s.MinimumLot != 75 || s.RegularLot != 75 || s.OpenInterest != 12345678901234567 || s.GenNum != 0 {
+		t.Errorf("integers %d %d %d %d", s.MinimumLot, s.RegularLot, s.OpenInterest, s.GenNum)
+	}
+	if s.StrikePrice.String() != "2400000.5" || s.PriceTick.String() != "5" {
+		t.Errorf("decimals %s %s", s.StrikePrice, s.PriceTick)
+	}
+}
+
+func TestDecodeScripCollectsFieldErrors(t *testing.T) {
+
+	_, err := entities.DecodeScrip(map[string]interface{}{
+		"symbol": []interface{}{"x"}, "minimumLot": "1.5", "strikePrice": "abc",
+	})
+
+	var decodeErr *entities.DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("error %v, want a *DecodeError", err)
+	}
+	fields := make(map[string]string)
+	for _, f := range decodeErr.Fields {
+		fields[f.Field] = f.Reason
+	}
+	want := map[string]string{"symbol": "expected string", "minimumLot": "expected integer", "strikePrice": "invalid number"}
+	for field, reason := range want {
+		if fields[field] != reason {
+			t.Errorf("%s: %q, want %q", field, fields[field], reason)
+		}
+	}
+	if len(fields) != len(want) {
+		t.Errorf("field errors %v, want %v", fields, want)
+	}
+}
@@ -0,0 +1,56 @@
+package entities
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"main.go/decimal"
+)
+
+// scripFields maps the json name of every Scrip field to its index.
+var scripFields = func() map[string]int {
+
+	fields := make(map[string]int)
+	t := reflect.TypeOf(Scrip{})
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = i
+		}
+	}
+	return fields
+}()
+
+// ScripFields returns the json names of the Scrip fields, sorted.
+func ScripFields() []string {
+
+	names := make([]string, 0, len(scripFields))
+	for name := range scripFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Field returns a field of the scrip by its json name, numbers formatted in
+// base 10 and decimals with all of their digits.
+func (s Scrip) Field(name string) (string, bool) {
+
+	index, ok := scripFields[name]
+	if !ok {
+		return "", false
+	}
+
+	v := reflect.ValueOf(s).Field(index)
+	if d, ok := v.Interface().(decimal.Decimal); ok {
+		return d.String(), true
+	}
+	switch v.Kind() {
+	case reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	default:
+		return v.String(), true
+	}
+}
@@ -0,0 +1,240 @@
+package export
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Columns is the stable column order of every export file.
+var Columns = []string{
+	"nTokenMktID", "segment", "segmentId", "token", "symbol", "trdSymbol", "series",
+	"instrumentType", "assetClass", "isin", "expiryDate", "strikePrice", "optionType",
+	"lotSize", "regularLot", "tickSize", "freezeQty", "divider", "precision",
+	"priceNum", "priceDen", "details",
+}
+
+// valueKeys maps the export columns onto the procedure values of a scrip.
+var valueKeys = map[string]string{
+	"nTokenMktID":    "tokenMktId",
+	"segmentId":      "segmentId",
+	"token":          "symbol",
+	"symbol":         "symbolName",
+	"trdSymbol":      "trdSymbol",
+	"series":         "series",
+	"instrumentType": "instrumentType",
+	"assetClass":     "assetClass",
+	"isin":           "isinCode",
+	"expiryDate":     "expDate",
+	"strikePrice":    "strikePrice",
+	"optionType":     "optionType",
+	"lotSize":        "minimumLot",
+	"regularLot":     "regularLot",
+	"tickSize":       "priceTick",
+	"freezeQty":      "freezePercent",
+	"divider":        "divider",
+	"precision":      "precision",
+	"priceNum":       "priceNum",
+	"priceDen":       "priceDen",
+	"details":        "details",
+}
+
+// Row is one scrip keyed by export column.
+type Row map[string]string
+
+// FromValues builds an export row from the procedure values of a scrip.
+func FromValues(segment string, values map[string]string) Row {
+
+	row := Row{"segment": segment}
+	for column, key := range valueKeys {
+		row[column] = values[key]
+	}
+	return row
+}
+
+type Options struct {
+	Dir        string
+	Formats    []string
+	PerSegment bool
+	Gzip       bool
+	Manifest   bool
+}
+
+type File struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Rows   int    `json:"rows"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+type Manifest struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	Columns     []string  `json:"columns"`
+	Files       []File    `json:"files"`
+}
+
+// Write exports the rows in every requested format, one file per segment or
+// a single combined file, and optionally a manifest.json with checksums.
+func Write(opts Options, rows []Row) (Manifest, error) {
+
+	manifest := Manifest{GeneratedAt: time.Now(), Columns: Columns}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return manifest, err
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i]["segment"] != rows[j]["segment"] {
+			return rows[i]["segment"] < rows[j]["segment"]
+		}
+		return rows[i]["nTokenMktID"] < rows[j]["nTokenMktID"]
+	})
+
+	groups := map[string][]Row{"scripmaster": rows}
+	if opts.PerSegment {
+		groups = make(map[string][]Row)
+		for _, row := range rows {
+			name := "scripmaster_" + row["segment"]
+			groups[name] = append(groups[name], row)
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, format := range opts.Formats {
+			file, err := writeFile(opts, name, strings.ToLower(strings.TrimSpace(format)), groups[name])
+			if err != nil {
+				return manifest, err
+			}
+			manifest.Files = append(manifest.Files, file)
+		}
+	}
+
+	if opts.Manifest {
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return manifest, err
+		}
+		if err := os.WriteFile(filepath.Join(opts.Dir, "manifest.json"), data, 0644); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, nil
+}
+
+func writeFile(opts Options, name, format string, rows []Row) (File, error) {
+
+	file := File{Name: name + "." + format, Format: format, Rows: len(rows)}
+	if format != "csv" && format != "jsonl" {
+		return file, fmt.Errorf("unsupported export format %q", format)
+	}
+	if opts.Gzip {
+		file.Name += ".gz"
+	}
+
+	f, err := os.Create(filepath.Join(opts.Dir, file.Name))
+	if err != nil {
+		return file, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(f, hash)}
+	var w io.Writer = counter
+	var gz *gzip.Writer
+	if opts.Gzip {
+		gz = gzip.NewWriter(counter)
+		w = gz
+	}
+
+	if format == "csv" {
+		err = writeCSV(w, rows)
+	} else {
+		err = writeJSONL(w, rows)
+	}
+	if err != nil {
+		return file, err
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return file, err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return file, err
+	}
+
+	file.Bytes = counter.n
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return file, nil
+}
+
+func writeCSV(w io.Writer, rows []Row) error {
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(Columns))
+	for _, row := range rows {
+		for i, column := range Columns {
+			record[i] = row[column]
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSONL(w io.Writer, rows []Row) error {
+
+	for _, row := range rows {
+		// written field by field so the keys keep the column order
+		var b strings.Builder
+		b.WriteByte('{')
+		for i, column := range Columns {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			key, _ := json.Marshal(column)
+			value, _ := json.Marshal(row[column])
+			b.Write(key)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+		b.WriteString("}\n")
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,182 @@
+package export_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"main.go/export"
+)
+
+func rows() []export.Row {
+
+	return []export.Row{
+		export.FromValues("nse_fo", map[string]string{"tokenMktId": "35002_2", "segmentId": "2", "symbol": "35002", "symbolName": "BANKNIFTY", "trdSymbol": "BANKNIFTY30DECFUT", "minimumLot": "30"}),
+		export.FromValues("nse_cm", map[string]string{"tokenMktId": "2885_1", "segmentId": "1", "symbol": "2885", "symbolName": "RELIANCE", "trdSymbol": "RELIANCE-EQ", "isinCode": "INE002A01018", "details": `RELIANCE, "IND"`}),
+		export.FromValues("nse_fo", map[string]string{"tokenMktId": "35001_2", "segmentId": "2", "symbol": "35001", "symbolName": "NIFTY", "trdSymbol": "NIFTY30DECFUT", "minimumLot": "75"}),
+	}
+}
+
+func read(t *testing.T, path string, gzipped bool) []byte {
+
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gzipped {
+		return data
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
+
+func TestColumnOrder(t *testing.T) {
+
+	dir := t.TempDir()
+	if _, err := export.Write(export.Options{Dir: dir, Formats: []string{"csv", " JSONL "}}, rows()); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(read(t, filepath.Join(dir, "scripmaster.csv"), false))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records[0], export.Columns) {
+		t.Errorf("csv header %v, want %v", records[0], export.Columns)
+	}
+	var tokens []string
+	for _, record := range records[1:] {
+		tokens = append(tokens, record[0])
+	}
+	// by segment, then by token
+	if want := []string{"2885_1", "35001_2", "35002_2"}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("csv rows %v, want %v", tokens, want)
+	}
+	if details := records[1][len(export.Columns)-1]; details != `RELIANCE, "IND"` {
+		t.Errorf("csv details %q", details)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(read(t, filepath.Join(dir, "scripmaster.jsonl"), false)))
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		dec := json.NewDecoder(strings.NewReader(scanner.Text()))
+		if _, err := dec.Token(); err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, key.(string))
+			if _, err := dec.Token(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !reflect.DeepEqual(keys, export.Columns) {
+			t.Fatalf("jsonl keys %v, want %v", keys, export.Columns)
+		}
+	}
+	if lines != 3 {
+		t.Errorf("%d jsonl lines, want 3", lines)
+	}
+}
+
+func TestStableOutput(t *testing.T) {
+
+	opts := func(dir string) export.Options {
+		return export.Options{Dir: dir, Formats: []string{"csv", "jsonl"}, PerSegment: true, Manifest: true}
+	}
+	first, second := t.TempDir(), t.TempDir()
+
+	reversed := rows()
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	a, err := export.Write(opts(first), rows())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := export.Write(opts(second), reversed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(a.Files, b.Files) {
+		t.Errorf("manifests differ for the same rows:\n%+v\n%+v", a.Files, b.Files)
+	}
+	var names []string
+	for _, f := range a.Files {
+		names = append(names, f.Name)
+	}
+	want := []string{"scripmaster_nse_cm.csv", "scripmaster_nse_cm.jsonl", "scripmaster_nse_fo.csv", "scripmaster_nse_fo.jsonl"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("files %v, want %v", names, want)
+	}
+}
+
+func TestManifestChecksums(t *testing.T) {
+
+	for _, gzipped := range []bool{false, true} {
+		dir := t.TempDir()
+		written, err := export.Write(export.Options{Dir: dir, Formats: []string{"csv", "jsonl"}, PerSegment: true, Gzip: gzipped, Manifest: true}, rows())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var manifest export.Manifest
+		if err := json.Unmarshal(read(t, filepath.Join(dir, "manifest.json"), false), &manifest); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(manifest.Files, written.Files) || !reflect.DeepEqual(manifest.Columns, export.Columns) {
+			t.Errorf("gzip %v: manifest.json %+v, want %+v", gzipped, manifest, written)
+		}
+
+		rowCounts := map[string]int{"nse_cm": 1, "nse_fo": 2}
+		for _, f := range manifest.Files {
+			data := read(t, filepath.Join(dir, f.Name), false)
+			sum := sha256.Sum256(data)
+			if f.SHA256 != hex.EncodeToString(sum[:]) || f.Bytes != int64(len(data)) {
+				t.Errorf("%s: sha256 %s, %d bytes, file has %x, %d bytes", f.Name, f.SHA256, f.Bytes, sum, len(data))
+			}
+			if gzipped != strings.HasSuffix(f.Name, ".gz") {
+				t.Errorf("%s: gzip %v", f.Name, gzipped)
+			}
+			segment := strings.TrimPrefix(strings.SplitN(f.Name, ".", 2)[0], "scripmaster_")
+			if f.Rows != rowCounts[segment] {
+				t.Errorf("%s: %d rows, want %d", f.Name, f.Rows, rowCounts[segment])
+			}
+			// the plain content is readable behind the gzip layer
+			if plain := read(t, filepath.Join(dir, f.Name), gzipped); len(plain) == 0 {
+				t.Errorf("%s is empty", f.Name)
+			}
+		}
+	}
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+
+	if _, err := export.Write(export.Options{Dir: t.TempDir(), Formats: []string{"xml"}}, rows()); err == nil {
+		t.Error("Write() accepted format xml")
+	}
+}
@@ -0,0 +1,107 @@
+package gates
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Config holds the gate limits, a zero or empty limit disables its gate.
+type Config struct {
+	MinRows             map[string]int
+	MaxDropPercent      float64
+	MaxSkipPercent      float64
+	RequiredUnderlyings map[string][]string
+}
+
+// Segment is what the gates see of one parsed segment. Previous is the
+// number of rows of the segment in the live master, negative when unknown.
+type Segment struct {
+	Code     string
+	Received int
+	Skipped  int
+	Rows     int
+	Previous int
+	Symbols  map[string]bool
+}
+
+// Violation is one tripped gate.
+type Violation struct {
+	Segment string
+	Gate    string
+	Detail  string
+}
+
+type Report struct {
+	Violations []Violation
+}
+
+func (r Report) Passed() bool {
+	return len(r.Violations) == 0
+}
+
+// Err returns the violations as one error, nil when every gate passed.
+func (r Report) Err() error {
+
+	if r.Passed() {
+		return nil
+	}
+	msgs := make([]string, 0, len(r.Violations))
+	for _, v := range r.Violations {
+		msgs = append(msgs, v.Segment+" "+v.Gate+": "+v.Detail)
+	}
+	return fmt.Errorf("%d gate(s) tripped: %s", len(r.Violations), strings.Join(msgs, "; "))
+}
+
+// Write prints the violations as a table.
+func (r Report) Write(w io.Writer) {
+
+	out := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "SEGMENT\tGATE\tDETAIL")
+	for _, v := range r.Violations {
+		fmt.Fprintf(out, "%s\t%s\t%s\n", v.Segment, v.Gate, v.Detail)
+	}
+	out.Flush()
+}
+
+// Check runs every gate over the segments.
+func Check(cfg Config, segments []Segment) Report {
+
+	var report Report
+	trip := func(segment, gate, format string, args ...interface{}) {
+		report.Violations = append(report.Violations, Violation{Segment: segment, Gate: gate, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	for _, s := range segments {
+
+		if min := cfg.MinRows[s.Code]; min > 0 && s.Rows < min {
+			trip(s.Code, "min_rows", "%d rows, at least %d expected", s.Rows, min)
+		}
+
+		if cfg.MaxDropPercent > 0 && s.Previous > 0 && s.Rows < s.Previous {
+			drop := float64(s.Previous-s.Rows) * 100 / float64(s.Previous)
+			if drop > cfg.MaxDropPercent {
+				trip(s.Code, "max_drop_percent", "%d rows against %d in the live master, %.1f%% drop exceeds %.1f%%", s.Rows, s.Previous, drop, cfg.MaxDropPercent)
+			}
+		}
+
+		if cfg.MaxSkipPercent > 0 && s.Received > 0 {
+			skip := float64(s.Skipped) * 100 / float64(s.Received)
+			if skip > cfg.MaxSkipPercent {
+				trip(s.Code, "max_skip_percent", "%d of %d records skipped, %.1f%% exceeds %.1f%%", s.Skipped, s.Received, skip, cfg.MaxSkipPercent)
+			}
+		}
+
+		var missing []string
+		for _, symbol := range cfg.RequiredUnderlyings[s.Code] {
+			if !s.Symbols[symbol] {
+				missing = append(missing, symbol)
+			}
+		}
+		if len(missing) > 0 {
+			trip(s.Code, "required_underlyings", "missing %s", strings.Join(missing, ", "))
+		}
+	}
+	return report
+}
@@ -0,0 +1,99 @@
+package gates_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"main.go/gates"
+)
+
+func TestCheck(t *testing.T) {
+
+	cfg := gates.Config{
+		MinRows:             map[string]int{"nse_fo": 100},
+		MaxDropPercent:      10,
+		MaxSkipPercent:      20,
+		RequiredUnderlyings: map[string][]string{"nse_fo": {"NIFTY", "BANKNIFTY"}},
+	}
+	required := map[string]bool{"NIFTY": true, "BANKNIFTY": true}
+
+	// seg is a segment that passes every gate of cfg
+	seg := func(change func(*gates.Segment)) gates.Segment {
+		s := gates.Segment{Code: "nse_fo", Received: 1000, Skipped: 100, Rows: 900, Previous: 950, Symbols: required}
+		change(&s)
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		segment gates.Segment
+		trips   string
+	}{
+		{"all pass", seg(func(s *gates.Segment) {}), ""},
+
+		{"rows below minimum", seg(func(s *gates.Segment) { s.Rows, s.Previous = 99, 99 }), "min_rows"},
+		{"rows at minimum", seg(func(s *gates.Segment) { s.Rows, s.Previous = 100, 100 }), ""},
+		{"rows above minimum", seg(func(s *gates.Segment) { s.Rows, s.Previous = 101, 101 }), ""},
+
+		{"drop above limit", seg(func(s *gates.Segment) { s.Rows, s.Previous = 899, 1000 }), "max_drop_percent"},
+		{"drop at limit", seg(func(s *gates.Segment) { s.Rows, s.Previous = 900, 1000 }), ""},
+		{"drop below limit", seg(func(s *gates.Segment) { s.Rows, s.Previous = 901, 1000 }), ""},
+		{"growth", seg(func(s *gates.Segment) { s.Rows, s.Previous = 900, 500 }), ""},
+		{"no previous rows", seg(func(s *gates.Segment) { s.Previous = 0 }), ""},
+		{"previous unknown", seg(func(s *gates.Segment) { s.Previous = -1 }), ""},
+		{"everything dropped", seg(func(s *gates.Segment) { s.Received, s.Skipped, s.Rows = 0, 0, 0 }), "min_rows,max_drop_percent"},
+
+		{"skips above limit", seg(func(s *gates.Segment) { s.Skipped = 201 }), "max_skip_percent"},
+		{"skips at limit", seg(func(s *gates.Segment) { s.Skipped = 200 }), ""},
+		{"skips below limit", seg(func(s *gates.Segment) { s.Skipped = 199 }), ""},
+		{"nothing received", seg(func(s *gates.Segment) { s.Received, s.Skipped = 0, 0 }), ""},
+
+		{"underlying missing", seg(func(s *gates.Segment) { s.Symbols = map[string]bool{"NIFTY": true} }), "required_underlyings"},
+		{"no underlyings", seg(func(s *gates.Segment) { s.Symbols = nil }), "required_underlyings"},
+
+		{"gates of another segment", seg(func(s *gates.Segment) { s.Code, s.Rows, s.Symbols = "mcx_fo", 900, nil }), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := gates.Check(cfg, []gates.Segment{tt.segment})
+
+			var tripped []string
+			for _, v := range report.Violations {
+				tripped = append(tripped, v.Gate)
+			}
+			if got := strings.Join(tripped, ","); got != tt.trips {
+				t.Errorf("tripped %q, want %q: %v", got, tt.trips, report.Violations)
+			}
+			if report.Passed() != (tt.trips == "") || (report.Err() == nil) != report.Passed() {
+				t.Errorf("Passed() %v, Err() %v", report.Passed(), report.Err())
+			}
+		})
+	}
+}
+
+func TestDisabledGates(t *testing.T) {
+
+	s := gates.Segment{Code: "nse_fo", Received: 100, Skipped: 100, Rows: 0, Previous: 1000}
+	if report := gates.Check(gates.Config{MinRows: map[string]int{"nse_fo": 0}}, []gates.Segment{s}); !report.Passed() {
+		t.Errorf("zero limits tripped %v", report.Violations)
+	}
+}
+
+func TestReport(t *testing.T) {
+
+	report := gates.Check(gates.Config{RequiredUnderlyings: map[string][]string{"nse_fo": {"NIFTY", "BANKNIFTY"}}},
+		[]gates.Segment{{Code: "nse_fo", Rows: 1}})
+
+	err := report.Err()
+	if err == nil || !strings.Contains(err.Error(), "nse_fo required_underlyings: missing NIFTY, BANKNIFTY") {
+		t.Errorf("Err() = %v", err)
+	}
+
+	var out bytes.Buffer
+	report.Write(&out)
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "nse_fo") {
+		t.Errorf("Write() = %q", out.String())
+	}
+}
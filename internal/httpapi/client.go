@@ -0,0 +1,164 @@
+// Package httpapi is the retrying JSON client shared by the AMX and Mojo
+// api clients.
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Config is the retry policy of a client. Name is the api named in the
+// retry log.
+type Config struct {
+	Name        string
+	Timeout     time.Duration
+	MaxRetries  int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// Error is returned for every failed call.
+type Error struct {
+	Op         string
+	URL        string
+	StatusCode int
+	Code       string
+	Message    string
+	Attempts   int
+	Err        error
+}
+
+func (e *Error) Error() string {
+
+	msg := e.Op + " failed"
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" with status %d", e.StatusCode)
+	}
+	if e.Code != "" {
+		msg += " [" + e.Code + "]"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Attempts > 1 {
+		msg += fmt.Sprintf(" (after %d attempts)", e.Attempts)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// retryable reports whether another attempt may succeed.
+func (e *Error) retryable() bool {
+	return e.StatusCode == 0 && e.Err != nil && !errors.Is(e.Err, ErrDecode) ||
+		e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// ErrDecode is wrapped by the errors of response bodies that do not
+// decode, they are not retried.
+var ErrDecode = errors.New("invalid response body")
+
+type Client struct {
+	cfg  Config
+	http *http.Client
+}
+
+func NewClient(cfg Config) *Client {
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
+	if cfg.BackoffBase <= 0 {
+		cfg.BackoffBase = 500 * time.Millisecond
+	}
+	if cfg.BackoffMax < cfg.BackoffBase {
+		cfg.BackoffMax = cfg.BackoffBase
+	}
+	return &Client{cfg: cfg, http: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Do sends the request built by newRequest, retrying transport errors, 429
+// and 5xx responses with bounded exponential backoff, and decodes the body
+// of a 2xx response into out. Every failure is an *Error.
+func (c *Client) Do(ctx context.Context, op, reqUrl string, newRequest func() (*http.Request, error), out interface{}) error {
+
+	var last *Error
+	for attempt := 1; attempt <= c.cfg.MaxRetries+1; attempt++ {
+
+		if attempt > 1 {
+			wait := c.backoff(attempt - 1)
+			log.Warn().Str("Operation", op).Int("Attempt", attempt).Dur("Backoff", wait).Err(last).Msg("Retrying " + c.cfg.Name + " call")
+			select {
+			case <-ctx.Done():
+				return &Error{Op: op, URL: reqUrl, Attempts: attempt - 1, Err: ctx.Err()}
+			case <-time.After(wait):
+			}
+		}
+
+		last = c.attempt(op, reqUrl, newRequest, out)
+		if last == nil {
+			return nil
+		}
+		last.Attempts = attempt
+		if !last.retryable() || ctx.Err() != nil {
+			return last
+		}
+	}
+	return last
+}
+
+func (c *Client) attempt(op, reqUrl string, newRequest func() (*http.Request, error), out interface{}) *Error {
+
+	req, err := newRequest()
+	if err != nil {
+		return &Error{Op: op, URL: reqUrl, Err: err}
+	}
+
+	response, err := c.http.Do(req)
+	if err != nil {
+		return &Error{Op: op, URL: reqUrl, Err: err}
+	}
+	defer response.Body.Close()
+
+	res, err := io.ReadAll(response.Body)
+	if err != nil {
+		return &Error{Op: op, URL: reqUrl, StatusCode: response.StatusCode, Err: err}
+	}
+	log.Info().Str("Operation", op).Stringer("Requesting Url", req.URL).Int("Status", response.StatusCode).Int("Bytes", len(res)).Msg("")
+	log.Debug().Str("Operation", op).Bytes("Response", res).Msg("")
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return &Error{Op: op, URL: reqUrl, StatusCode: response.StatusCode, Message: http.StatusText(response.StatusCode)}
+	}
+
+	if err := json.Unmarshal(res, out); err != nil {
+		return &Error{Op: op, URL: reqUrl, StatusCode: response.StatusCode, Err: fmt.Errorf("%w: %v", ErrDecode, err)}
+	}
+	return nil
+}
+
+func (c *Client) backoff(retry int) time.Duration {
+
+	wait := c.cfg.BackoffBase << uint(retry-1)
+	if wait <= 0 || wait > c.cfg.BackoffMax {
+		wait = c.cfg.BackoffMax
+	}
+	// up to 20% jitter so parallel calls do not retry in lockstep
+	return wait - time.Duration(rand.Int63n(int64(wait)/5+1))
+}
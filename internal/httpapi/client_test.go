@@ -0,0 +1,151 @@
+package httpapi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"main.go/internal/httpapi"
+)
+
+type result struct {
+	OK bool `json:"ok"`
+}
+
+// serve answers the calls with the statuses in turn, the last status is
+// repeated. A 200 answers with body.
+func serve(t *testing.T, body string, statuses ...int) (*httptest.Server, *int32) {
+
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		status := statuses[len(statuses)-1]
+		if n <= len(statuses) {
+			status = statuses[n-1]
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(body))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func call(ctx context.Context, client *httpapi.Client, url string) (result, error) {
+
+	var out result
+	err := client.Do(ctx, "test", url, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}, &out)
+	return out, err
+}
+
+func newClient(retries int) *httpapi.Client {
+	return httpapi.NewClient(httpapi.Config{Name: "test", Timeout: 2 * time.Second, MaxRetries: retries, BackoffBase: time.Millisecond, BackoffMax: 2 * time.Millisecond})
+}
+
+func TestRetries(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantCalls int32
+		wantCode  int
+	}{
+		{"success", []int{200}, 1, 0},
+		{"5xx retried", []int{500, 502, 200}, 3, 0},
+		{"429 retried", []int{429, 200}, 2, 0},
+		{"5xx until retries run out", []int{503}, 4, 503},
+		{"4xx not retried", []int{400, 200}, 1, 400},
+		{"401 not retried", []int{401, 200}, 1, 401},
+		{"404 not retried", []int{404, 200}, 1, 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := serve(t, `{"ok": true}`, tt.statuses...)
+			out, err := call(context.Background(), newClient(3), server.URL)
+
+			if got := atomic.LoadInt32(calls); got != tt.wantCalls {
+				t.Errorf("%d calls, want %d", got, tt.wantCalls)
+			}
+			if tt.wantCode == 0 {
+				if err != nil || !out.OK {
+					t.Errorf("Do() = %+v, %v", out, err)
+				}
+				return
+			}
+
+			var apiErr *httpapi.Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("error %v, want an *httpapi.Error", err)
+			}
+			if apiErr.StatusCode != tt.wantCode || apiErr.Attempts != int(tt.wantCalls) || apiErr.Op != "test" || apiErr.URL != server.URL {
+				t.Errorf("error %+v, want status %d after %d attempts", apiErr, tt.wantCode, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTransportErrorsRetried(t *testing.T) {
+
+	server, _ := serve(t, "", 200)
+	url := server.URL
+	server.Close()
+
+	_, err := call(context.Background(), newClient(2), url)
+	var apiErr *httpapi.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 0 || apiErr.Err == nil || apiErr.Attempts != 3 {
+		t.Errorf("error %+v, want a transport error after 3 attempts", err)
+	}
+}
+
+func TestDecodeErrorNotRetried(t *testing.T) {
+
+	server, calls := serve(t, `{"ok": `, 200)
+	_, err := call(context.Background(), newClient(3), server.URL)
+
+	if !errors.Is(err, httpapi.ErrDecode) {
+		t.Errorf("error %v, want ErrDecode", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("%d calls, want 1", got)
+	}
+}
+
+func TestBackoffStopsOnCancel(t *testing.T) {
+
+	server, calls := serve(t, "", 503)
+	client := httpapi.NewClient(httpapi.Config{Name: "test", MaxRetries: 5, BackoffBase: time.Hour, BackoffMax: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	started := time.Now()
+	_, err := call(ctx, client, server.URL)
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("Do() returned after %v, want it to stop on cancel", elapsed)
+	}
+
+	var apiErr *httpapi.Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, context.Canceled) || apiErr.Attempts != 1 {
+		t.Errorf("error %+v, want context.Canceled after 1 attempt", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("%d calls, want 1", got)
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+
+	err := &httpapi.Error{Op: "getAllSecInfo", StatusCode: 503, Code: "E1", Message: "Service Unavailable", Attempts: 3}
+	if want := "getAllSecInfo failed with status 503 [E1]: Service Unavailable (after 3 attempts)"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
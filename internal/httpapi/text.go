@@ -0,0 +1,38 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Text accepts a JSON string, number, bool or null.
+type Text string
+
+func (t *Text) UnmarshalJSON(data []byte) error {
+
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*t = ""
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*t = Text(s)
+		return nil
+	}
+	*t = Text(data)
+	return nil
+}
+
+func (t Text) String() string {
+	return string(t)
+}
+
+// Success reports whether a response message is "success" in any case.
+func Success(message Text) bool {
+	return strings.EqualFold(string(message), "success")
+}
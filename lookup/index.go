@@ -0,0 +1,151 @@
+package lookup
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"main.go/export"
+)
+
+// Index is an in-memory view of the scrip master. A reload builds a new
+// snapshot and swaps it in, so lookups never see a half loaded master.
+type Index struct {
+	mu   sync.RWMutex
+	snap *snapshot
+}
+
+type snapshot struct {
+	rows         []export.Row
+	byToken      map[string]int
+	byISIN       map[string][]int
+	bySymbol     map[string][]int
+	byUnderlying map[string][]int
+	loadedAt     time.Time
+}
+
+func NewIndex() *Index {
+	return &Index{snap: &snapshot{}}
+}
+
+// Load replaces the contents of the index. The index keeps its own copy of
+// rows, ordered by segment and token.
+func (idx *Index) Load(rows []export.Row) {
+
+	rows = append([]export.Row(nil), rows...)
+	snap := &snapshot{
+		rows:         rows,
+		byToken:      make(map[string]int, len(rows)),
+		byISIN:       make(map[string][]int),
+		bySymbol:     make(map[string][]int, len(rows)),
+		byUnderlying: make(map[string][]int),
+		loadedAt:     time.Now(),
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i]["segment"] != rows[j]["segment"] {
+			return rows[i]["segment"] < rows[j]["segment"]
+		}
+		return rows[i]["nTokenMktID"] < rows[j]["nTokenMktID"]
+	})
+
+	for i, row := range rows {
+		snap.byToken[tokenKey(row["segment"], row["token"])] = i
+		if row["segmentId"] != "" {
+			snap.byToken[tokenKey(row["segmentId"], row["token"])] = i
+		}
+		if isin := key(row["isin"]); isin != "" {
+			snap.byISIN[isin] = append(snap.byISIN[isin], i)
+		}
+		if symbol := key(row["trdSymbol"]); symbol != "" {
+			snap.bySymbol[symbol] = append(snap.bySymbol[symbol], i)
+		}
+		if underlying := key(row["symbol"]); underlying != "" {
+			snap.byUnderlying[underlying] = append(snap.byUnderlying[underlying], i)
+		}
+	}
+
+	idx.mu.Lock()
+	idx.snap = snap
+	idx.mu.Unlock()
+}
+
+func (idx *Index) current() *snapshot {
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.snap
+}
+
+// Size returns the number of scrips and the time they were loaded.
+func (idx *Index) Size() (int, time.Time) {
+
+	snap := idx.current()
+	return len(snap.rows), snap.loadedAt
+}
+
+// Token looks up a scrip by segment (exchange code or market segment id) and token.
+func (idx *Index) Token(segment, token string) (export.Row, bool) {
+
+	snap := idx.current()
+	i, ok := snap.byToken[tokenKey(segment, token)]
+	if !ok {
+		return nil, false
+	}
+	return snap.rows[i], true
+}
+
+func (idx *Index) ISIN(isin string) []export.Row {
+
+	snap := idx.current()
+	return snap.pick(snap.byISIN[key(isin)])
+}
+
+func (idx *Index) Symbol(trdSymbol string) []export.Row {
+
+	snap := idx.current()
+	return snap.pick(snap.bySymbol[key(trdSymbol)])
+}
+
+func (idx *Index) Underlying(underlying string) []export.Row {
+
+	snap := idx.current()
+	return snap.pick(snap.byUnderlying[key(underlying)])
+}
+
+// Search returns the scrips whose trading symbol or symbol contains query,
+// optionally limited to one segment.
+func (idx *Index) Search(query, segment string) []export.Row {
+
+	snap := idx.current()
+	query = key(query)
+
+	var found []export.Row
+	for _, row := range snap.rows {
+		if segment != "" && row["segment"] != segment && row["segmentId"] != segment {
+			continue
+		}
+		if strings.Contains(key(row["trdSymbol"]), query) || strings.Contains(key(row["symbol"]), query) {
+			found = append(found, row)
+		}
+	}
+	return found
+}
+
+func (snap *snapshot) pick(positions []int) []export.Row {
+
+	rows := make([]export.Row, 0, len(positions))
+	for _, i := range positions {
+		rows = append(rows, snap.rows[i])
+	}
+	return rows
+}
+
+func tokenKey(segment, token string) string {
+	return strings.ToLower(segment) + "|" + token
+}
+
+func key(value string) string {
+	return strings.ToUpper(strings.TrimSpace(value))
+}
@@ -0,0 +1,223 @@
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"main.go/export"
+)
+
+// maxPageSize caps the size query parameter.
+const maxPageSize = 1000
+
+// Server exposes the index over read-only HTTP JSON endpoints:
+//
+//	GET  /scrips/{segment}/{token}
+//	GET  /isin/{isin}
+//	GET  /symbol/{trdSymbol}
+//	GET  /underlying/{name}?page=&size=
+//	GET  /search?q=&segment=&page=&size=
+//	GET  /health
+//	POST /reload
+type Server struct {
+	Index    *Index
+	Load     func() ([]export.Row, error)
+	Marker   string
+	Poll     time.Duration
+	PageSize int
+}
+
+// Reload loads the master again and swaps it into the index.
+func (s *Server) Reload() error {
+
+	rows, err := s.Load()
+	if err != nil {
+		return err
+	}
+	s.Index.Load(rows)
+	log.Info().Int("Rows", len(rows)).Msg("Scrip master index loaded")
+	return nil
+}
+
+// ListenAndServe serves addr until ctx is cancelled. The index is reloaded
+// whenever the build marker file changes.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+
+	if err := s.Reload(); err != nil {
+		return err
+	}
+
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	go s.watchMarker(ctx)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	log.Info().Str("Address", addr).Msg("Scrip master lookup service started")
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) watchMarker(ctx context.Context) {
+
+	if s.Marker == "" || s.Poll <= 0 {
+		return
+	}
+
+	var last time.Time
+	if info, err := os.Stat(s.Marker); err == nil {
+		last = info.ModTime()
+	}
+
+	ticker := time.NewTicker(s.Poll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(s.Marker)
+			if err != nil || !info.ModTime().After(last) {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				log.Error().Err(err).Msg("Unable to reload scrip master index after build")
+				continue
+			}
+			last = info.ModTime()
+		}
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scrips/", s.get(func(w http.ResponseWriter, r *http.Request, parts []string) {
+		if len(parts) != 2 {
+			writeError(w, http.StatusBadRequest, "expected /scrips/{segment}/{token}")
+			return
+		}
+		row, ok := s.Index.Token(parts[0], parts[1])
+		if !ok {
+			writeError(w, http.StatusNotFound, "scrip not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, row)
+	}))
+	mux.HandleFunc("/isin/", s.list(s.Index.ISIN))
+	mux.HandleFunc("/symbol/", s.list(s.Index.Symbol))
+	mux.HandleFunc("/underlying/", s.list(s.Index.Underlying))
+	mux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		if q == "" {
+			writeError(w, http.StatusBadRequest, "missing query parameter q")
+			return
+		}
+		s.writePage(w, r, s.Index.Search(q, r.URL.Query().Get("segment")))
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		rows, loadedAt := s.Index.Size()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"rows": rows, "loadedAt": loadedAt})
+	})
+	mux.HandleFunc("/reload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "use POST")
+			return
+		}
+		if err := s.Reload(); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		rows, loadedAt := s.Index.Size()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"rows": rows, "loadedAt": loadedAt})
+	})
+	return mux
+}
+
+func (s *Server) get(handle func(http.ResponseWriter, *http.Request, []string)) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "use GET")
+			return
+		}
+		path := strings.Trim(r.URL.Path, "/")
+		parts := strings.Split(path, "/")[1:]
+		handle(w, r, parts)
+	}
+}
+
+func (s *Server) list(lookup func(string) []export.Row) http.HandlerFunc {
+
+	return s.get(func(w http.ResponseWriter, r *http.Request, parts []string) {
+		if len(parts) != 1 || parts[0] == "" {
+			writeError(w, http.StatusBadRequest, "expected a single lookup value")
+			return
+		}
+		s.writePage(w, r, lookup(parts[0]))
+	})
+}
+
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, rows []export.Row) {
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = s.PageSize
+	}
+	if size < 1 {
+		size = 50
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	// page past the last one, checked before multiplying so a huge page
+	// cannot overflow
+	start := len(rows)
+	if page-1 <= len(rows)/size {
+		start = min(len(rows), (page-1)*size)
+	}
+	end := min(len(rows), start+size)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":   len(rows),
+		"page":    page,
+		"size":    size,
+		"results": rows[start:end],
+	})
+}
+
+func min(a, b int) int {
+
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
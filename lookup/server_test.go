@@ -0,0 +1,192 @@
+package lookup_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"main.go/export"
+	"main.go/lookup"
+)
+
+func rows(n int) []export.Row {
+
+	rows := make([]export.Row, n)
+	for i := range rows {
+		token := fmt.Sprintf("%d", 35001+i)
+		rows[i] = export.Row{
+			"nTokenMktID": token + "_2", "segment": "nse_fo", "segmentId": "2", "token": token,
+			"symbol": "NIFTY", "trdSymbol": "NIFTY" + token, "isin": "",
+		}
+	}
+	return rows
+}
+
+func newServer(t *testing.T, load func() ([]export.Row, error)) *httptest.Server {
+
+	t.Helper()
+	s := &lookup.Server{Index: lookup.NewIndex(), Load: load, PageSize: 2}
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, method, url string, out interface{}) int {
+
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+type page struct {
+	Total   int
+	Page    int
+	Size    int
+	Results []export.Row
+}
+
+func TestPagination(t *testing.T) {
+
+	server := newServer(t, func() ([]export.Row, error) { return rows(5), nil })
+
+	tests := []struct {
+		query      string
+		page, size int
+		tokens     []string
+	}{
+		{"", 1, 2, []string{"35001", "35002"}},
+		{"page=2", 2, 2, []string{"35003", "35004"}},
+		{"page=3", 3, 2, []string{"35005"}},
+		{"page=4", 4, 2, nil},
+		{"page=0&size=0", 1, 2, []string{"35001", "35002"}},
+		{"page=-3&size=-1", 1, 2, []string{"35001", "35002"}},
+		{"page=x&size=y", 1, 2, []string{"35001", "35002"}},
+		{"page=2&size=3", 2, 3, []string{"35004", "35005"}},
+		{"size=5", 1, 5, []string{"35001", "35002", "35003", "35004", "35005"}},
+		{"size=1000000", 1, 1000, []string{"35001", "35002", "35003", "35004", "35005"}},
+		{"page=4611686018427387905&size=3", 4611686018427387905, 3, nil},
+		{"page=9223372036854775807&size=1000", 9223372036854775807, 1000, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got page
+			if status := get(t, http.MethodGet, server.URL+"/underlying/nifty?"+tt.query, &got); status != http.StatusOK {
+				t.Fatalf("status %d", status)
+			}
+			if got.Total != 5 || got.Page != tt.page || got.Size != tt.size {
+				t.Errorf("total %d, page %d, size %d, want 5, %d, %d", got.Total, got.Page, got.Size, tt.page, tt.size)
+			}
+			var tokens []string
+			for _, row := range got.Results {
+				tokens = append(tokens, row["token"])
+			}
+			if !reflect.DeepEqual(tokens, tt.tokens) {
+				t.Errorf("tokens %v, want %v", tokens, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestScripLookup(t *testing.T) {
+
+	server := newServer(t, func() ([]export.Row, error) { return rows(2), nil })
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/scrips/nse_fo/35001", http.StatusOK},
+		{"/scrips/NSE_FO/35002", http.StatusOK},
+		{"/scrips/2/35001", http.StatusOK},
+		{"/scrips/bse_cm/35001", http.StatusNotFound},
+		{"/scrips/nse_fo/99999", http.StatusNotFound},
+		{"/scrips/nse_fo", http.StatusBadRequest},
+		{"/symbol/", http.StatusBadRequest},
+		{"/search", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if status := get(t, http.MethodGet, server.URL+tt.path, nil); status != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.path, status, tt.status)
+		}
+	}
+
+	var unknown page
+	get(t, http.MethodGet, server.URL+"/search?q=nifty&segment=mcx_fo", &unknown)
+	if unknown.Total != 0 || len(unknown.Results) != 0 {
+		t.Errorf("search of an unknown segment found %d rows", unknown.Total)
+	}
+}
+
+func TestReload(t *testing.T) {
+
+	n, fail := 2, false
+	server := newServer(t, func() ([]export.Row, error) {
+		if fail {
+			return nil, errors.New("master unavailable")
+		}
+		return rows(n), nil
+	})
+
+	if status := get(t, http.MethodGet, server.URL+"/reload", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("GET /reload: status %d", status)
+	}
+
+	n = 3
+	var health struct{ Rows int }
+	if status := get(t, http.MethodPost, server.URL+"/reload", &health); status != http.StatusOK || health.Rows != 3 {
+		t.Fatalf("POST /reload: status %d, rows %d, want 200, 3", status, health.Rows)
+	}
+	if status := get(t, http.MethodGet, server.URL+"/scrips/nse_fo/35003", nil); status != http.StatusOK {
+		t.Errorf("reloaded scrip: status %d", status)
+	}
+
+	// a failed reload keeps the loaded index
+	fail = true
+	if status := get(t, http.MethodPost, server.URL+"/reload", nil); status != http.StatusInternalServerError {
+		t.Errorf("failed reload: status %d", status)
+	}
+	get(t, http.MethodGet, server.URL+"/health", &health)
+	if health.Rows != 3 {
+		t.Errorf("rows %d after a failed reload, want 3", health.Rows)
+	}
+}
+
+func TestLoadKeepsCallerOrder(t *testing.T) {
+
+	loaded := rows(3)
+	loaded[0], loaded[2] = loaded[2], loaded[0]
+	want := []string{loaded[0]["token"], loaded[1]["token"], loaded[2]["token"]}
+
+	idx := lookup.NewIndex()
+	idx.Load(loaded)
+
+	for i, row := range loaded {
+		if row["token"] != want[i] {
+			t.Fatalf("Load reordered the rows of the caller: %v", loaded)
+		}
+	}
+	if got := idx.Underlying("NIFTY"); got[0]["token"] != "35001" {
+		t.Errorf("index not ordered by token: first %s", got[0]["token"])
+	}
+}
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"main.go/constants"
-	"main.go/services"
 	service "main.go/services"
 	configs "main.go/utils/config"
 	flag "main.go/utils/flags"
@@ -14,15 +19,43 @@ func init() {
 
 func main() {
 
-	amx_config := &service.AMXConfig{AppConfig: configs.Get(constants.ApplicationConfig), UrlConfig: configs.Get(constants.APIConfig), DBConfig: configs.Get(constants.DatabaseConfig), ISBackupDone: false}
-	service.AMXScripmaster.Init(amx_config)
-	services.AMXScripmaster.BackUp_AMXScripMaster(amx_config)
-	accToken := service.AMXScripmaster.Login(amx_config)
-	service.AMXScripmaster.Build(amx_config, accToken)
-	service.AMXScripmaster.Build_MarketCap(amx_config)
-	service.AMXScripmaster.UpdateStockID(amx_config)
+	command := flag.Command()
+	err := run(command)
+	service.LogStatus(command, err)
+	os.Exit(service.ExitCode(err))
+}
+
+// run executes the command and returns the error of the step that failed.
+func run(command string) error {
+
+	amx_config := &service.AMXConfig{AppConfig: configs.Get(constants.ApplicationConfig), UrlConfig: configs.Get(constants.APIConfig), DBConfig: configs.Get(constants.DatabaseConfig), RulesConfig: configs.Get(constants.RulesConfig), ISBackupDone: false, DryRun: flag.DryRun(), Force: flag.Force()}
+	if err := service.AMXScripmaster.Init(amx_config); err != nil {
+		return err
+	}
+	defer service.AMXScripmaster.Close(amx_config)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch command {
+	case constants.RestoreCommand:
+		return service.AMXScripmaster.Restore_AMXScripMaster(amx_config, flag.AssetClass(), flag.Generation())
+	case constants.ServeCommand:
+		return service.AMXScripmaster.Serve(amx_config, ctx)
+	case constants.ExportCommand:
+		return service.AMXScripmaster.Export_Master(amx_config)
+	case constants.BackUpsCommand:
+		return backUps(amx_config, flag.CommandArg(0), flag.CommandArg(1))
+	case constants.BuildCommand:
+		return service.AMXScripmaster.Run_Build(amx_config, ctx)
+	case constants.RebuildCommand:
+		return service.AMXScripmaster.Run_Rebuild(amx_config, ctx, flag.FromArchive())
+	default:
+		return &service.StepError{Class: service.ValidationFailure, Step: "command", Details: "expected build, rebuild, restore, backups, export or serve", Err: fmt.Errorf("unknown command %q", command)}
+	}
 }
 
 func initconfig() {
+	flag.Parse()
 	configs.Init(flag.BaseConfigPath())
 }
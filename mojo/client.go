@@ -0,0 +1,101 @@
+// Package mojo is the client of the Mojo stock master api.
+package mojo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"main.go/internal/httpapi"
+)
+
+type Config struct {
+	URL         string
+	Timeout     time.Duration
+	MaxRetries  int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// Error is returned for every failed Mojo call.
+type Error = httpapi.Error
+
+type StockMasterResponse struct {
+	Status  httpapi.Text `json:"status"`
+	Message httpapi.Text `json:"message"`
+	Data    struct {
+		StockMaster []Stock `json:"stock_master"`
+	} `json:"data"`
+}
+
+// Stock links a Mojo stock id to an ISIN. Mojo sends missing values as
+// null or as the string "null", both decode to an empty value.
+type Stock struct {
+	SID  string
+	ISIN string
+}
+
+func (s *Stock) UnmarshalJSON(data []byte) error {
+
+	var raw struct {
+		SID  httpapi.Text `json:"sid"`
+		ISIN httpapi.Text `json:"isin"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	s.SID, s.ISIN = clean(raw.SID), clean(raw.ISIN)
+	return nil
+}
+
+func clean(t httpapi.Text) string {
+
+	v := strings.TrimSpace(t.String())
+	if strings.EqualFold(v, "null") {
+		return ""
+	}
+	return v
+}
+
+type Client struct {
+	cfg  Config
+	http *httpapi.Client
+}
+
+func NewClient(cfg Config) *Client {
+
+	return &Client{cfg: cfg, http: httpapi.NewClient(httpapi.Config{
+		Name:        "Mojo",
+		Timeout:     cfg.Timeout,
+		MaxRetries:  cfg.MaxRetries,
+		BackoffBase: cfg.BackoffBase,
+		BackoffMax:  cfg.BackoffMax,
+	})}
+}
+
+// StockMaster fetches the stock master. Transport errors, 429 and 5xx
+// responses are retried with bounded exponential backoff, a response that
+// does not report success fails.
+func (c *Client) StockMaster(ctx context.Context) ([]Stock, error) {
+
+	const op = "getStockMaster"
+
+	var res StockMasterResponse
+	err := c.http.Do(ctx, op, c.cfg.URL, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.URL, nil)
+	}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if !httpapi.Success(res.Message) {
+		message := res.Message.String()
+		if message == "" {
+			message = "response without message"
+		}
+		return nil, &Error{Op: op, URL: c.cfg.URL, Message: message}
+	}
+	return res.Data.StockMaster, nil
+}
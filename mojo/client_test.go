@@ -0,0 +1,104 @@
+package mojo_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"main.go/mojo"
+)
+
+type reply struct {
+	status int
+	body   string
+}
+
+func TestStockMaster(t *testing.T) {
+
+	success := reply{http.StatusOK, `{"status": true, "message": "Success", "data": {"stock_master": [
+		{"sid": "RELIANCE", "isin": "INE002A01018"},
+		{"sid": 1234, "isin": "INE467B01029"},
+		{"sid": null, "isin": "null"}
+	]}}`}
+	stocks := []mojo.Stock{
+		{SID: "RELIANCE", ISIN: "INE002A01018"},
+		{SID: "1234", ISIN: "INE467B01029"},
+		{SID: "", ISIN: ""},
+	}
+
+	tests := []struct {
+		name     string
+		replies  []reply
+		want     []mojo.Stock
+		wantErr  string
+		requests int32
+	}{
+		{name: "decodes null and numeric values", replies: []reply{success}, want: stocks, requests: 1},
+		{name: "message case", replies: []reply{{http.StatusOK, `{"message": "SUCCESS", "data": {"stock_master": []}}`}}, want: []mojo.Stock{}, requests: 1},
+		{name: "retries server errors", replies: []reply{{http.StatusBadGateway, ""}, {http.StatusTooManyRequests, ""}, success}, want: stocks, requests: 3},
+		{name: "gives up after the retries", replies: []reply{{http.StatusServiceUnavailable, ""}}, wantErr: "status 503", requests: 3},
+		{name: "client errors are not retried", replies: []reply{{http.StatusNotFound, ""}}, wantErr: "status 404", requests: 1},
+		{name: "failure message", replies: []reply{{http.StatusOK, `{"status": false, "message": "Failure", "data": null}`}}, wantErr: "Failure", requests: 1},
+		{name: "missing message", replies: []reply{{http.StatusOK, `{"data": {"stock_master": []}}`}}, wantErr: "without message", requests: 1},
+		{name: "malformed body is not retried", replies: []reply{{http.StatusOK, `{"message": "Success", "data": {"stock_master": {}}}`}}, wantErr: "invalid response body", requests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(atomic.AddInt32(&requests, 1))
+				if n > len(tt.replies) {
+					n = len(tt.replies)
+				}
+				w.WriteHeader(tt.replies[n-1].status)
+				w.Write([]byte(tt.replies[n-1].body))
+			}))
+			defer server.Close()
+
+			client := mojo.NewClient(mojo.Config{URL: server.URL, Timeout: time.Second, MaxRetries: 2, BackoffBase: time.Millisecond, BackoffMax: time.Millisecond})
+			got, err := client.StockMaster(context.Background())
+
+			if tt.wantErr != "" {
+				var mojoErr *mojo.Error
+				if err == nil || !errors.As(err, &mojoErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want a *mojo.Error with %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if n := atomic.LoadInt32(&requests); n != tt.requests {
+				t.Errorf("%d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestStockMasterTimeout(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := mojo.NewClient(mojo.Config{URL: server.URL, Timeout: 50 * time.Millisecond, BackoffBase: time.Millisecond})
+	started := time.Now()
+	if _, err := client.StockMaster(context.Background()); err == nil {
+		t.Fatal("no error after the timeout")
+	}
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("returned after %v", elapsed)
+	}
+}
@@ -1,11 +1,74 @@
 package persistance
 
 import (
-	"database/sql"
+	"context"
+	"time"
+
+	"main.go/diff"
+	"main.go/entities"
+	"main.go/export"
+	"main.go/persistance/quarantine"
+)
+
+// Target selects the live master or the shadow tables of a swap load.
+type Target int
+
+const (
+	Live Target = iota
+	Staging
 )
 
+// BackUp is one backup generation of the scrip master, tagged with the run
+// that took it.
+type BackUp struct {
+	RunID   string
+	TakenAt time.Time
+	Rows    int
+}
+
+// StockID links a stock master id to an ISIN.
+type StockID struct {
+	SID  string
+	ISIN string
+}
+
+// StockIDUpdate counts the stock ids a store was given. Updated is the
+// number of ids it applied to the scrips with their ISIN, Failed the
+// number it could not apply.
+type StockIDUpdate struct {
+	Updated int
+	Failed  int
+}
+
+// Database is the storage of the scrip master. Asset classes are
+// constants.AssetEquity and constants.AssetDerivative, segments are
+// reported by market segment id. Flush makes the writes of a direct load
+// durable, for stores that do not commit every call.
 type Database interface {
-	GetDBConnection() *sql.DB
-	Reconnect() (*sql.DB, bool)
-	MssqlConnCheck() bool
+	BackUp(ctx context.Context, runID string, runTime time.Time) error
+	ListBackUps(ctx context.Context) ([]BackUp, error)
+	InspectBackUp(ctx context.Context, runID string) (map[string]int, error)
+	PruneBackUp(ctx context.Context, runID string) error
+	Restore(ctx context.Context, runID string, assetClasses []string) error
+
+	Delete(ctx context.Context, assetClass string) error
+	UpsertEquity(ctx context.Context, target Target, scrips []entities.EquityScrip) error
+	UpsertDerivative(ctx context.Context, target Target, scrips []entities.DerivativeScrip) error
+
+	PrepareBulk(ctx context.Context) error
+	BulkDerivative(ctx context.Context, scrips []entities.DerivativeScrip) (int64, error)
+	MergeBulk(ctx context.Context, target Target, runID string) error
+
+	PrepareStaging(ctx context.Context) error
+	StagedCounts(ctx context.Context) (map[string]int, error)
+	SwapStaging(ctx context.Context) error
+
+	RefreshMarketCap(ctx context.Context) error
+	UpdateStockIDs(ctx context.Context, ids []StockID) (StockIDUpdate, error)
+
+	Snapshot(ctx context.Context) ([]diff.Record, error)
+	ReadMaster(ctx context.Context) ([]export.Row, error)
+	Quarantine(ctx context.Context, records []quarantine.Record) error
+
+	Flush(ctx context.Context) error
 }
@@ -0,0 +1,146 @@
+package dryrun
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	mssqldb "github.com/denisenkom/go-mssqldb"
+	"main.go/persistance/mssql"
+)
+
+// Writer records the stored procedure calls a run would make, as one JSONL
+// and one CSV file per procedure, instead of executing them.
+type Writer struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string]*procFiles
+}
+
+type procFiles struct {
+	jsonl *os.File
+	csv   *os.File
+	w     *csv.Writer
+	enc   *json.Encoder
+	calls int
+}
+
+func NewWriter(dir string) (*Writer, error) {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Writer{dir: dir, files: make(map[string]*procFiles)}, nil
+}
+
+func (w *Writer) Dir() string {
+	return w.dir
+}
+
+// Call binds the values exactly as the database call would and writes the
+// resulting parameters.
+func (w *Writer) Call(proc mssql.Procedure, values map[string]string) error {
+
+	args, err := proc.Args(values)
+	if err != nil {
+		return err
+	}
+
+	params := make(map[string]interface{}, len(args))
+	row := make([]string, 0, len(args))
+	for _, arg := range args {
+		named := arg.(sql.NamedArg)
+		value := plain(named.Value)
+		params["@"+named.Name] = value
+		if value == nil {
+			row = append(row, "")
+		} else {
+			row = append(row, fmt.Sprint(value))
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	files, err := w.open(proc)
+	if err != nil {
+		return err
+	}
+	files.calls++
+	if err := files.enc.Encode(map[string]interface{}{"proc": proc.Name, "params": params}); err != nil {
+		return err
+	}
+	return files.w.Write(row)
+}
+
+// Calls returns the number of calls written per procedure.
+func (w *Writer) Calls() map[string]int {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	calls := make(map[string]int, len(w.files))
+	for name, files := range w.files {
+		calls[name] = files.calls
+	}
+	return calls
+}
+
+func (w *Writer) Close() error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var firstErr error
+	for _, files := range w.files {
+		files.w.Flush()
+		for _, err := range []error{files.w.Error(), files.csv.Close(), files.jsonl.Close()} {
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (w *Writer) open(proc mssql.Procedure) (*procFiles, error) {
+
+	if files, ok := w.files[proc.Name]; ok {
+		return files, nil
+	}
+
+	jsonl, err := os.Create(filepath.Join(w.dir, proc.Name+".jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	csvFile, err := os.Create(filepath.Join(w.dir, proc.Name+".csv"))
+	if err != nil {
+		jsonl.Close()
+		return nil, err
+	}
+
+	files := &procFiles{jsonl: jsonl, csv: csvFile, w: csv.NewWriter(csvFile), enc: json.NewEncoder(jsonl)}
+	header := make([]string, 0, len(proc.Params))
+	for _, p := range proc.Params {
+		header = append(header, "@"+strings.TrimPrefix(p.Name, "@"))
+	}
+	if err := files.w.Write(header); err != nil {
+		return nil, err
+	}
+
+	w.files[proc.Name] = files
+	return files, nil
+}
+
+func plain(value interface{}) interface{} {
+
+	if v, ok := value.(mssqldb.VarChar); ok {
+		return string(v)
+	}
+	return value
+}
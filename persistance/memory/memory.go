@@ -0,0 +1,411 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"main.go/constants"
+	"main.go/diff"
+	"main.go/entities"
+	"main.go/export"
+	"main.go/persistance"
+	"main.go/persistance/quarantine"
+)
+
+// Store keeps the scrip master in memory, rows keyed by token. With a Path
+// the state is saved to that JSON file and read back by New, so runs of the
+// CLI can follow each other without a database. The file is written at the
+// load boundaries, the swap, a bulk merge, a delete, the backups and a
+// Flush, not on every upsert, and the staging and bulk rows of a load are
+// never saved. Market cap refresh is not supported and does nothing.
+type Store struct {
+	Path string
+
+	mu    sync.Mutex
+	state state
+}
+
+type row struct {
+	Class  string            `json:"class"`
+	Values map[string]string `json:"values"`
+}
+
+type backUp struct {
+	RunID   string         `json:"runId"`
+	TakenAt time.Time      `json:"takenAt"`
+	Rows    map[string]row `json:"rows"`
+}
+
+type state struct {
+	Live        map[string]row      `json:"live"`
+	Staging     map[string]row      `json:"-"`
+	Bulk        []row               `json:"-"`
+	BackUps     []backUp            `json:"backUps"`
+	StockIDs    map[string]string   `json:"stockIds"`
+	Quarantined []quarantine.Record `json:"quarantined"`
+}
+
+// New returns a store persisted to path, or an in-memory only store when
+// path is empty.
+func New(path string) (*Store, error) {
+
+	s := &Store{Path: path}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return nil, err
+		default:
+			if err := json.Unmarshal(data, &s.state); err != nil {
+				return nil, fmt.Errorf("memory store %s: %w", path, err)
+			}
+		}
+	}
+	if s.state.Live == nil {
+		s.state.Live = make(map[string]row)
+	}
+	if s.state.Staging == nil {
+		s.state.Staging = make(map[string]row)
+	}
+	if s.state.StockIDs == nil {
+		s.state.StockIDs = make(map[string]string)
+	}
+	return s, nil
+}
+
+// save writes the state to Path. The caller holds mu.
+func (s *Store) save() error {
+
+	if s.Path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+func (s *Store) BackUp(ctx context.Context, runID string, runTime time.Time) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := backUp{RunID: runID, TakenAt: runTime, Rows: copyRows(s.state.Live)}
+	kept := s.state.BackUps[:0]
+	for _, existing := range s.state.BackUps {
+		if existing.RunID != runID {
+			kept = append(kept, existing)
+		}
+	}
+	s.state.BackUps = append(kept, b)
+	return s.save()
+}
+
+// ListBackUps returns the backups newest first.
+func (s *Store) ListBackUps(ctx context.Context) ([]persistance.BackUp, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	generations := make([]persistance.BackUp, 0, len(s.state.BackUps))
+	for _, b := range s.state.BackUps {
+		generations = append(generations, persistance.BackUp{RunID: b.RunID, TakenAt: b.TakenAt, Rows: len(b.Rows)})
+	}
+	sort.SliceStable(generations, func(i, j int) bool {
+		return generations[i].TakenAt.After(generations[j].TakenAt)
+	})
+	return generations, nil
+}
+
+func (s *Store) InspectBackUp(ctx context.Context, runID string) (map[string]int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	if b, ok := s.find(runID); ok {
+		for _, r := range b.Rows {
+			counts[r.Values["segmentId"]]++
+		}
+	}
+	return counts, nil
+}
+
+func (s *Store) PruneBackUp(ctx context.Context, runID string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.state.BackUps[:0]
+	for _, b := range s.state.BackUps {
+		if b.RunID != runID {
+			kept = append(kept, b)
+		}
+	}
+	s.state.BackUps = kept
+	return s.save()
+}
+
+// Restore replaces the live rows of the asset classes with the rows of the
+// backup.
+func (s *Store) Restore(ctx context.Context, runID string, assetClasses []string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.find(runID)
+	if !ok {
+		return fmt.Errorf("backup %s not found", runID)
+	}
+	for _, class := range assetClasses {
+		s.deleteClass(class)
+		for token, r := range b.Rows {
+			if r.Class == class {
+				s.state.Live[token] = copyRow(r)
+			}
+		}
+	}
+	return s.save()
+}
+
+func (s *Store) Delete(ctx context.Context, assetClass string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteClass(assetClass)
+	return s.save()
+}
+
+func (s *Store) UpsertEquity(ctx context.Context, target persistance.Target, scrips []entities.EquityScrip) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := s.rows(target)
+	for _, eq := range scrips {
+		rows[eq.TokenMktID] = row{Class: constants.AssetEquity, Values: persistance.EquityValues(eq)}
+	}
+	return nil
+}
+
+func (s *Store) UpsertDerivative(ctx context.Context, target persistance.Target, scrips []entities.DerivativeScrip) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := s.rows(target)
+	for _, derv := range scrips {
+		rows[derv.TokenMktID] = row{Class: constants.AssetDerivative, Values: persistance.DerivativeValues(derv)}
+	}
+	return nil
+}
+
+func (s *Store) PrepareBulk(ctx context.Context) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Bulk = nil
+	return nil
+}
+
+func (s *Store) BulkDerivative(ctx context.Context, scrips []entities.DerivativeScrip) (int64, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, derv := range scrips {
+		s.state.Bulk = append(s.state.Bulk, row{Class: constants.AssetDerivative, Values: persistance.DerivativeValues(derv)})
+	}
+	return int64(len(scrips)), nil
+}
+
+func (s *Store) MergeBulk(ctx context.Context, target persistance.Target, runID string) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := s.rows(target)
+	for _, r := range s.state.Bulk {
+		rows[r.Values["tokenMktId"]] = r
+	}
+	s.state.Bulk = nil
+	return s.save()
+}
+
+func (s *Store) PrepareStaging(ctx context.Context) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Staging = make(map[string]row)
+	return nil
+}
+
+func (s *Store) StagedCounts(ctx context.Context) (map[string]int, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, r := range s.state.Staging {
+		counts[r.Values["segmentId"]]++
+	}
+	return counts, nil
+}
+
+func (s *Store) SwapStaging(ctx context.Context) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Live, s.state.Staging = s.state.Staging, make(map[string]row)
+	return s.save()
+}
+
+func (s *Store) RefreshMarketCap(ctx context.Context) error {
+	return nil
+}
+
+// UpdateStockIDs sets the stock id of the live rows with the same ISIN.
+// Every id is applied, like a procedure call that updates no row when the
+// ISIN is not in the master.
+func (s *Store) UpdateStockIDs(ctx context.Context, ids []persistance.StockID) (persistance.StockIDUpdate, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byISIN := make(map[string][]string)
+	for token, r := range s.state.Live {
+		byISIN[r.Values["isinCode"]] = append(byISIN[r.Values["isinCode"]], token)
+	}
+
+	for _, id := range ids {
+		s.state.StockIDs[id.ISIN] = id.SID
+		for _, token := range byISIN[id.ISIN] {
+			s.state.Live[token].Values["stockId"] = id.SID
+		}
+	}
+	if err := s.save(); err != nil {
+		return persistance.StockIDUpdate{Failed: len(ids)}, err
+	}
+	return persistance.StockIDUpdate{Updated: len(ids)}, nil
+}
+
+func (s *Store) Snapshot(ctx context.Context) ([]diff.Record, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]diff.Record, 0, len(s.state.Live))
+	for _, r := range s.state.Live {
+		records = append(records, persistance.SnapshotRecord(r.Values))
+	}
+	return records, nil
+}
+
+func (s *Store) ReadMaster(ctx context.Context) ([]export.Row, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens := make([]string, 0, len(s.state.Live))
+	for token := range s.state.Live {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+
+	master := make([]export.Row, 0, len(tokens))
+	for _, token := range tokens {
+		master = append(master, export.FromValues("", s.state.Live[token].Values))
+	}
+	return master, nil
+}
+
+func (s *Store) Quarantine(ctx context.Context, records []quarantine.Record) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Quarantined = append(s.state.Quarantined, records...)
+	return s.save()
+}
+
+func (s *Store) Flush(ctx context.Context) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.save()
+}
+
+// Len returns the number of live rows.
+func (s *Store) Len() int {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.state.Live)
+}
+
+func (s *Store) rows(target persistance.Target) map[string]row {
+
+	if target == persistance.Staging {
+		return s.state.Staging
+	}
+	return s.state.Live
+}
+
+func (s *Store) deleteClass(class string) {
+
+	for token, r := range s.state.Live {
+		if r.Class == class {
+			delete(s.state.Live, token)
+		}
+	}
+}
+
+func (s *Store) find(runID string) (backUp, bool) {
+
+	for _, b := range s.state.BackUps {
+		if b.RunID == runID {
+			return b, true
+		}
+	}
+	return backUp{}, false
+}
+
+func copyRows(rows map[string]row) map[string]row {
+
+	copied := make(map[string]row, len(rows))
+	for token, r := range rows {
+		copied[token] = copyRow(r)
+	}
+	return copied
+}
+
+func copyRow(r row) row {
+
+	values := make(map[string]string, len(r.Values))
+	for k, v := range r.Values {
+		values[k] = v
+	}
+	return row{Class: r.Class, Values: values}
+}
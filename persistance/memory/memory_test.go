@@ -0,0 +1,144 @@
+package memory_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"main.go/constants"
+	"main.go/entities"
+	"main.go/persistance"
+	"main.go/persistance/memory"
+)
+
+func equities(tokens ...string) []entities.EquityScrip {
+
+	scrips := make([]entities.EquityScrip, 0, len(tokens))
+	for _, token := range tokens {
+		scrips = append(scrips, entities.EquityScrip{TokenMktID: token, SegmentID: "1"})
+	}
+	return scrips
+}
+
+func derivatives(tokens ...string) []entities.DerivativeScrip {
+
+	scrips := make([]entities.DerivativeScrip, 0, len(tokens))
+	for _, token := range tokens {
+		scrips = append(scrips, entities.DerivativeScrip{TokenMktID: token, SegmentID: "2"})
+	}
+	return scrips
+}
+
+// reopen reads the store back from its file, as the next run does.
+func reopen(t *testing.T, path string) *memory.Store {
+
+	t.Helper()
+	s, err := memory.New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestUpsertsSavedOnFlush(t *testing.T) {
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.json")
+	s := reopen(t, path)
+
+	if err := s.Delete(ctx, constants.AssetEquity); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.UpsertEquity(ctx, persistance.Live, equities("2885_1", "1594_1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(saved.ModTime()) || info.Size() != saved.Size() {
+		t.Fatalf("upserts wrote the file: %v, %v", info, err)
+	}
+	if n := reopen(t, path).Len(); n != 0 {
+		t.Errorf("%d rows saved before the flush, want 0", n)
+	}
+
+	if err := s.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := reopen(t, path).Len(); n != 2 {
+		t.Errorf("%d rows saved after the flush, want 2", n)
+	}
+}
+
+func TestSwapSavesLiveOnly(t *testing.T) {
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.json")
+	s := reopen(t, path)
+
+	if err := s.PrepareStaging(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PrepareBulk(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpsertEquity(ctx, persistance.Staging, equities("2885_1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.BulkDerivative(ctx, derivatives("35001_2", "35002_2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("staging wrote the file: %v", err)
+	}
+
+	if err := s.MergeBulk(ctx, persistance.Staging, "run-1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := reopen(t, path).Len(); n != 0 {
+		t.Errorf("%d live rows saved before the swap, want 0", n)
+	}
+	if err := s.SwapStaging(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	read := reopen(t, path)
+	if n := read.Len(); n != 3 {
+		t.Errorf("%d live rows saved after the swap, want 3", n)
+	}
+	// the staging rows of the load are not carried into the next run
+	if counts, err := read.StagedCounts(ctx); err != nil || len(counts) != 0 {
+		t.Errorf("staged counts %v, %v after reopening", counts, err)
+	}
+}
+
+func TestBackUpSaved(t *testing.T) {
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store.json")
+	s := reopen(t, path)
+
+	if err := s.UpsertDerivative(ctx, persistance.Live, derivatives("35001_2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BackUp(ctx, "run-1", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	read := reopen(t, path)
+	backUps, err := read.ListBackUps(ctx)
+	if err != nil || len(backUps) != 1 || backUps[0].RunID != "run-1" || backUps[0].Rows != 1 {
+		t.Fatalf("backups %+v, %v", backUps, err)
+	}
+	if err := read.Restore(ctx, "run-1", []string{constants.AssetDerivative}); err != nil {
+		t.Fatal(err)
+	}
+	if n := reopen(t, path).Len(); n != 1 {
+		t.Errorf("%d rows after restoring, want 1", n)
+	}
+}
@@ -0,0 +1,173 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	mssqldb "github.com/denisenkom/go-mssqldb"
+	"github.com/spf13/viper"
+)
+
+// BulkTable is a staging table filled with bulk copy, configured in
+// database.yaml. Columns map the table columns onto the named values of a
+// record the same way procedure parameters do.
+type BulkTable struct {
+	Table   string  `mapstructure:"table"`
+	Prepare string  `mapstructure:"prepare"`
+	Columns []Param `mapstructure:"columns"`
+}
+
+func LoadBulkTable(config *viper.Viper, key string) (BulkTable, error) {
+
+	var table BulkTable
+	if err := config.UnmarshalKey(key, &table); err != nil {
+		return table, fmt.Errorf("bulk table %s: %w", key, err)
+	}
+	if table.Table == "" || len(table.Columns) == 0 {
+		return table, fmt.Errorf("bulk table %s: table and columns have to be configured", key)
+	}
+	for _, c := range table.Columns {
+		if c.Name == "" || c.Field == "" {
+			return table, fmt.Errorf("bulk table %s: column needs both name and field", key)
+		}
+		if _, err := bindValue(c.Type, ""); err != nil {
+			return table, fmt.Errorf("bulk table %s: column %s: %w", key, c.Name, err)
+		}
+	}
+	return table, nil
+}
+
+// Row binds the named values of a record to the table columns.
+func (t BulkTable) Row(values map[string]string) ([]interface{}, error) {
+
+	row := make([]interface{}, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		value, ok := values[c.Field]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown field %q for column %s", t.Table, c.Field, c.Name)
+		}
+		bound, err := bindValue(c.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: column %s: %w", t.Table, c.Name, err)
+		}
+		// bulk copy converts by the column type of the table
+		if v, ok := bound.(mssqldb.VarChar); ok {
+			bound = string(v)
+		}
+		row = append(row, bound)
+	}
+	return row, nil
+}
+
+func (t BulkTable) columnNames() []string {
+
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// BulkCopy copies rows into the table in batches of batchSize rows, each in
+// its own transaction, with up to parallelism batches in flight. It returns
+// the number of rows copied.
+func BulkCopy(ctx context.Context, db *sql.DB, t BulkTable, rows [][]interface{}, batchSize, parallelism int) (int64, error) {
+
+	if batchSize <= 0 {
+		batchSize = len(rows)
+	}
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	batches := make(chan [][]interface{})
+	go func() {
+		defer close(batches)
+		for start := 0; start < len(rows); start += batchSize {
+			end := start + batchSize
+			if end > len(rows) {
+				end = len(rows)
+			}
+			select {
+			case batches <- rows[start:end]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu     sync.Mutex
+		copied int64
+		first  error
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				n, err := t.copyBatch(ctx, db, batch)
+				mu.Lock()
+				copied += n
+				if err != nil && first == nil {
+					first = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if first == nil && ctx.Err() != nil {
+		first = ctx.Err()
+	}
+	return copied, first
+}
+
+func (t BulkTable) copyBatch(ctx context.Context, db *sql.DB, batch [][]interface{}) (int64, error) {
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, mssqldb.CopyIn(t.Table, mssqldb.BulkOptions{RowsPerBatch: len(batch)}, t.columnNames()...))
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for _, row := range batch {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	// an Exec without arguments flushes the batch to the server
+	result, err := stmt.ExecContext(ctx)
+	if err != nil {
+		stmt.Close()
+		tx.Rollback()
+		return 0, err
+	}
+	stmt.Close()
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
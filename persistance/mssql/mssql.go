@@ -3,20 +3,24 @@ package mssql
 import (
 	_ "github.com/denisenkom/go-mssqldb"
 	"main.go/constants"
-	configs "main.go/utils/config"
 
 	"database/sql"
 
 	"github.com/rs/zerolog/log"
 
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
 )
 
 type MSSQL struct {
 	Server, Database, User, Password string
 	Port                             int
+	BatchSize, Parallelism           int
+
+	pool    *Pool
+	procs   Procedures
+	queries map[string]string
 }
 
 func (mssql MSSQL) GetDBConnection() (*sql.DB, error) {
@@ -31,51 +35,26 @@ func (mssql MSSQL) GetDBConnection() (*sql.DB, error) {
 	return db, connErr
 }
 
-func (mssql MSSQL) Reconnect() (*sql.DB, bool) {
-
-	appConfig := configs.Get(constants.ApplicationConfig)
-	val, _ := strconv.Atoi(appConfig.GetString(constants.Retry))
-	attempt, n := val, val
-
-	for attempt > 0 {
-
-		log.Warn().Stack().Int("Attempt", n-(attempt-1)).Int("Total Attempt", n).Msg("Reconnecting...")
-		db, _ := mssql.GetDBConnection()
-
-		if IsConnected(db) {
-			return db, true
-		}
-		attempt--
-	}
-
-	return nil, false
+// Open sets up the connection pool shared by every copy of mssql. The
+// first connection is made by the first step that needs one.
+func (mssql *MSSQL) Open(cfg PoolConfig) {
+	mssql.pool = NewPool(cfg, mssql.GetDBConnection)
 }
 
-func (mssql MSSQL) MssqlConnCheck(db *sql.DB) bool {
-	var flag bool
-	if !IsConnected(db) {
-		db, flag = mssql.Reconnect()
-		if !flag {
-			log.Error().Stack().Str("Server", mssql.Server).Msg("Unable to connect database")
-			return false
-		}
+// Close closes the shared connection pool.
+func (mssql MSSQL) Close() error {
+
+	if mssql.pool == nil {
+		return nil
 	}
-	return true
+	return mssql.pool.Close()
 }
 
-func IsConnected(dbConn *sql.DB) bool {
-
-	c := context.Background()
-	err := dbConn.PingContext(c)
+// connect returns the shared, health checked connection pool.
+func (mssql MSSQL) connect(ctx context.Context) (*sql.DB, error) {
 
-	if err != nil {
-		log.Error().Stack().Err(err).Msg("Error While Creating mssql Connection")
-		return false
+	if mssql.pool == nil {
+		return nil, errors.New("MSSQL - connection pool not opened")
 	}
-
-	return true
-}
-
-func CloseDBConnection(dbConn *sql.DB) error {
-	return dbConn.Close()
+	return mssql.pool.DB(ctx)
 }
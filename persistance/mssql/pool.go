@@ -0,0 +1,153 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrCircuitOpen is returned while the breaker is open after repeated
+// connection failures.
+var ErrCircuitOpen = errors.New("MSSQL - circuit open, connection attempts suspended")
+
+// PoolConfig sizes the connection pool and tunes its recovery. Retry is the
+// number of reconnect attempts after a failed connect, BreakerThreshold the
+// number of failed connects after which the breaker opens for
+// BreakerCooldown. Zero values disable the health check interval and the
+// breaker.
+type PoolConfig struct {
+	MaxOpen, MaxIdle                int
+	ConnMaxLifetime, HealthInterval time.Duration
+	Retry                           int
+	BackoffBase, BackoffMax         time.Duration
+	BreakerThreshold                int
+	BreakerCooldown                 time.Duration
+}
+
+// Pool is the connection manager shared by every step of a run. It keeps
+// one *sql.DB, checks its health before handing it out and replaces it
+// when the check fails.
+type Pool struct {
+	cfg  PoolConfig
+	open func() (*sql.DB, error)
+
+	mu        sync.Mutex
+	db        *sql.DB
+	checked   time.Time
+	failures  int
+	openUntil time.Time
+}
+
+func NewPool(cfg PoolConfig, open func() (*sql.DB, error)) *Pool {
+	return &Pool{cfg: cfg, open: open}
+}
+
+// DB returns the shared pool, connecting or reconnecting when needed.
+func (p *Pool) DB(ctx context.Context) (*sql.DB, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.breakerOpen(now) {
+		return nil, ErrCircuitOpen
+	}
+
+	if p.db != nil {
+		if p.cfg.HealthInterval > 0 && now.Sub(p.checked) < p.cfg.HealthInterval {
+			return p.db, nil
+		}
+		err := p.db.PingContext(ctx)
+		if err == nil {
+			p.checked = now
+			return p.db, nil
+		}
+		log.Warn().Err(err).Msg("MSSQL health check failed, reconnecting")
+		p.db.Close()
+		p.db = nil
+	}
+
+	db, err := p.connect(ctx)
+	if err != nil {
+		p.failures++
+		if p.cfg.BreakerThreshold > 0 && p.failures >= p.cfg.BreakerThreshold {
+			p.openUntil = time.Now().Add(p.cfg.BreakerCooldown)
+			log.Error().Int("Failures", p.failures).Dur("Cooldown", p.cfg.BreakerCooldown).Msg("MSSQL circuit opened")
+		}
+		return nil, err
+	}
+
+	p.db, p.checked, p.failures = db, time.Now(), 0
+	return db, nil
+}
+
+// breakerOpen reports whether connects are suspended. Once the cooldown has
+// passed a single connect is let through, its failure opens the breaker
+// again.
+func (p *Pool) breakerOpen(now time.Time) bool {
+	return p.cfg.BreakerThreshold > 0 && p.failures >= p.cfg.BreakerThreshold && now.Before(p.openUntil)
+}
+
+// connect opens and pings a new pool, retrying with exponential backoff.
+func (p *Pool) connect(ctx context.Context) (*sql.DB, error) {
+
+	attempts := p.cfg.Retry + 1
+	var last error
+	for attempt := 1; attempt <= attempts; attempt++ {
+
+		if attempt > 1 {
+			wait := p.backoff(attempt - 1)
+			log.Warn().Int("Attempt", attempt).Int("Total Attempt", attempts).Dur("Backoff", wait).Err(last).Msg("Reconnecting...")
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		db, err := p.open()
+		if err == nil {
+			db.SetMaxOpenConns(p.cfg.MaxOpen)
+			if p.cfg.MaxIdle > 0 {
+				db.SetMaxIdleConns(p.cfg.MaxIdle)
+			}
+			db.SetConnMaxLifetime(p.cfg.ConnMaxLifetime)
+			if err = db.PingContext(ctx); err == nil {
+				return db, nil
+			}
+			db.Close()
+		}
+		last = err
+	}
+
+	log.Error().Err(last).Int("Attempts", attempts).Msg("Unable to connect database")
+	return nil, fmt.Errorf("MSSQL - Connection Inactive after %d attempts: %w", attempts, last)
+}
+
+func (p *Pool) backoff(retry int) time.Duration {
+
+	wait := p.cfg.BackoffBase << uint(retry-1)
+	if wait <= 0 || wait > p.cfg.BackoffMax {
+		wait = p.cfg.BackoffMax
+	}
+	return wait
+}
+
+// Close closes the shared pool.
+func (p *Pool) Close() error {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.db == nil {
+		return nil
+	}
+	err := p.db.Close()
+	p.db = nil
+	return err
+}
@@ -0,0 +1,104 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	mssqldb "github.com/denisenkom/go-mssqldb"
+	"github.com/spf13/viper"
+)
+
+// Param maps a stored procedure parameter onto a named value of the record
+// being written and the SQL Server type it is bound as.
+type Param struct {
+	Name  string `mapstructure:"name"`
+	Field string `mapstructure:"field"`
+	Type  string `mapstructure:"type"`
+}
+
+// Procedure is a stored procedure call configured in database.yaml.
+type Procedure struct {
+	Name   string  `mapstructure:"proc"`
+	Params []Param `mapstructure:"params"`
+}
+
+func LoadProcedure(config *viper.Viper, key string) (Procedure, error) {
+
+	var proc Procedure
+	if err := config.UnmarshalKey(key, &proc); err != nil {
+		return proc, fmt.Errorf("procedure %s: %w", key, err)
+	}
+	if proc.Name == "" {
+		return proc, fmt.Errorf("procedure %s: proc name not configured", key)
+	}
+	for _, p := range proc.Params {
+		if p.Name == "" || p.Field == "" {
+			return proc, fmt.Errorf("procedure %s: parameter needs both name and field", key)
+		}
+		if _, err := bindValue(p.Type, ""); err != nil {
+			return proc, fmt.Errorf("procedure %s: parameter @%s: %w", key, p.Name, err)
+		}
+	}
+	return proc, nil
+}
+
+// Args binds the named values of a record to the procedure parameters.
+func (proc Procedure) Args(values map[string]string) ([]interface{}, error) {
+
+	args := make([]interface{}, 0, len(proc.Params))
+	for _, p := range proc.Params {
+		value, ok := values[p.Field]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown field %q for parameter @%s", proc.Name, p.Field, p.Name)
+		}
+		bound, err := bindValue(p.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: parameter @%s: %w", proc.Name, p.Name, err)
+		}
+		args = append(args, sql.Named(strings.TrimPrefix(p.Name, "@"), bound))
+	}
+	return args, nil
+}
+
+func ExecProcedure(ctx context.Context, db *sql.DB, proc Procedure, values map[string]string) error {
+
+	args, err := proc.Args(values)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, proc.Name, args...)
+	return err
+}
+
+// bindValue converts a value to the Go type go-mssqldb sends as the given
+// SQL Server type. Empty values of numeric types are sent as NULL, decimals
+// are sent as text so the server converts them without a float round trip.
+func bindValue(sqlType, value string) (interface{}, error) {
+
+	switch strings.ToLower(sqlType) {
+	case "", "nvarchar":
+		return value, nil
+	case "varchar":
+		return mssqldb.VarChar(value), nil
+	case "decimal":
+		if value == "" {
+			return nil, nil
+		}
+		return mssqldb.VarChar(value), nil
+	case "int", "bigint":
+		if value == "" {
+			return nil, nil
+		}
+		return strconv.ParseInt(value, 10, 64)
+	case "float":
+		if value == "" {
+			return nil, nil
+		}
+		return strconv.ParseFloat(value, 64)
+	default:
+		return nil, fmt.Errorf("unsupported type %q", sqlType)
+	}
+}
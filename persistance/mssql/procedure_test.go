@@ -0,0 +1,72 @@
+package mssql_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	mssqldb "github.com/denisenkom/go-mssqldb"
+	"github.com/spf13/viper"
+	"main.go/constants"
+	"main.go/decimal"
+	"main.go/entities"
+	"main.go/persistance"
+	"main.go/persistance/mssql"
+)
+
+func TestFractionalPricesBind(t *testing.T) {
+
+	config := viper.New()
+	config.SetConfigFile(filepath.Join("..", "..", constants.BaseConfigPathDefaultValue, constants.DatabaseConfig))
+	if err := config.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	var store mssql.MSSQL
+	if err := store.Configure(config); err != nil {
+		t.Fatal(err)
+	}
+	procs := store.Procedures()
+
+	price := func(s string) decimal.Decimal {
+		d, err := decimal.Parse(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	values := persistance.DerivativeValues(entities.DerivativeScrip{
+		Scrip: entities.Scrip{
+			Symbol: "35001", StrikePrice: price("123.5"), BasePrice: price("12345.0"),
+			LowPriceRange: price("110.25"), HighPriceRange: price("136.75"), PriceTick: price("5"),
+		},
+		TokenMktID: "35001_2", SegmentID: "2", Divider: "100", Precision: "2",
+		PriceNumer: "1", PriceDenom: "1",
+	})
+	want := map[string]string{
+		"nStrikePrice": "123.5", "nBasePrice": "12345.0",
+		"nLowPriceRange": "110.25", "nHighPriceRange": "136.75",
+	}
+
+	for _, proc := range []mssql.Procedure{procs.DervInsert, procs.StagingDerv} {
+		args, err := proc.Args(values)
+		if err != nil {
+			t.Fatalf("%s: %v", proc.Name, err)
+		}
+		for _, arg := range args {
+			named := arg.(sql.NamedArg)
+			if w, ok := want[named.Name]; ok && named.Value != mssqldb.VarChar(w) {
+				t.Errorf("%s: @%s = %#v, want %q", proc.Name, named.Name, named.Value, w)
+			}
+		}
+	}
+
+	row, err := procs.Bulk.Row(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, column := range procs.Bulk.Columns {
+		if w, ok := want[column.Name]; ok && row[i] != w {
+			t.Errorf("%s: column %s = %#v, want %q", procs.Bulk.Table, column.Name, row[i], w)
+		}
+	}
+}
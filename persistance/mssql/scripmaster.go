@@ -0,0 +1,483 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"main.go/constants"
+	"main.go/diff"
+	"main.go/entities"
+	"main.go/export"
+	"main.go/persistance"
+	"main.go/persistance/quarantine"
+)
+
+// Procedures are the stored procedure calls of the scrip master.
+type Procedures struct {
+	EQInsert, DervInsert, StagingEQ, StagingDerv Procedure
+	StockID, Quarantine                          Procedure
+	BackUp, Prune, RestoreEQ, RestoreDerv        Procedure
+	BulkMerge, BulkMergeStaging                  Procedure
+	Bulk                                         BulkTable
+}
+
+// Configure loads the procedures and queries of database.yaml.
+func (mssql *MSSQL) Configure(config *viper.Viper) error {
+
+	procs := map[string]*Procedure{
+		constants.EQInsertQuery:     &mssql.procs.EQInsert,
+		constants.DERInsertQuery:    &mssql.procs.DervInsert,
+		constants.StockIDQuery:      &mssql.procs.StockID,
+		constants.BackUpProcedure:   &mssql.procs.BackUp,
+		constants.BackUpPrune:       &mssql.procs.Prune,
+		constants.RestoreEquity:     &mssql.procs.RestoreEQ,
+		constants.RestoreDerivative: &mssql.procs.RestoreDerv,
+		constants.QuarantineProc:    &mssql.procs.Quarantine,
+		constants.BulkMerge:         &mssql.procs.BulkMerge,
+		constants.BulkMergeStaging:  &mssql.procs.BulkMergeStaging,
+	}
+	for key, proc := range procs {
+		var err error
+		if *proc, err = LoadProcedure(config, key); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if mssql.procs.Bulk, err = LoadBulkTable(config, constants.BulkDerivative); err != nil {
+		return err
+	}
+
+	mssql.procs.StagingEQ, mssql.procs.StagingDerv = mssql.procs.EQInsert, mssql.procs.DervInsert
+	mssql.procs.StagingEQ.Name = config.GetString(constants.StagingEQProc)
+	mssql.procs.StagingDerv.Name = config.GetString(constants.StagingDervProc)
+
+	mssql.queries = make(map[string]string)
+	for _, key := range []string{
+		constants.DeleteEquity, constants.DeleteDerivative, constants.MarketCapQuery,
+		constants.PrepareStaging, constants.StagingCountQuery, constants.SwapStaging,
+		constants.BackUpList, constants.BackUpInspect, constants.MasterSnapshot, constants.ExportQuery,
+	} {
+		mssql.queries[key] = config.GetString(key)
+	}
+	return nil
+}
+
+// Procedures returns the configured procedure calls.
+func (mssql MSSQL) Procedures() Procedures {
+	return mssql.procs
+}
+
+func (mssql MSSQL) exec(ctx context.Context, key string) error {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	sQuery := mssql.queries[key]
+	if _, qErr := db.ExecContext(ctx, sQuery); qErr != nil {
+		log.Error().Str("Query", sQuery).Err(qErr).Msg("Query execution failed")
+		return qErr
+	}
+	return nil
+}
+
+func (mssql MSSQL) BackUp(ctx context.Context, runID string, runTime time.Time) error {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("Server", mssql.Server).Msg("Connected")
+	return ExecProcedure(ctx, db, mssql.procs.BackUp, runValues(runID, runTime))
+}
+
+func (mssql MSSQL) ListBackUps(ctx context.Context) ([]persistance.BackUp, error) {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sQuery := mssql.queries[constants.BackUpList]
+	rows, qErr := db.QueryContext(ctx, sQuery)
+	if qErr != nil {
+		log.Error().Str("Query", sQuery).Err(qErr).Msg("Error in listing backups")
+		return nil, qErr
+	}
+	defer rows.Close()
+
+	var generations []persistance.BackUp
+	for rows.Next() {
+		var g persistance.BackUp
+		if err := rows.Scan(&g.RunID, &g.TakenAt, &g.Rows); err != nil {
+			return nil, err
+		}
+		generations = append(generations, g)
+	}
+	return generations, rows.Err()
+}
+
+func (mssql MSSQL) InspectBackUp(ctx context.Context, runID string) (map[string]int, error) {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sQuery := mssql.queries[constants.BackUpInspect]
+	rows, qErr := db.QueryContext(ctx, sQuery, sql.Named("sRunID", runID))
+	if qErr != nil {
+		log.Error().Str("Query", sQuery).Str("Run ID", runID).Err(qErr).Msg("Error in inspecting backup")
+		return nil, qErr
+	}
+	defer rows.Close()
+
+	return scanCounts(rows)
+}
+
+func (mssql MSSQL) PruneBackUp(ctx context.Context, runID string) error {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ExecProcedure(ctx, db, mssql.procs.Prune, runValues(runID, time.Time{}))
+}
+
+// Restore runs the restore procedures of the asset classes in one
+// transaction.
+func (mssql MSSQL) Restore(ctx context.Context, runID string, assetClasses []string) error {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, txErr := db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return txErr
+	}
+
+	for _, class := range assetClasses {
+		proc := mssql.procs.RestoreEQ
+		if class == constants.AssetDerivative {
+			proc = mssql.procs.RestoreDerv
+		}
+		args, aErr := proc.Args(runValues(runID, time.Time{}))
+		if aErr != nil {
+			tx.Rollback()
+			return aErr
+		}
+		if _, qErr := tx.ExecContext(ctx, proc.Name, args...); qErr != nil {
+			tx.Rollback()
+			log.Error().Str("Procedure", proc.Name).Str("Asset Class", class).Err(qErr).Msg("Error in restoring AMX ScripMaster")
+			return qErr
+		}
+	}
+	return tx.Commit()
+}
+
+func (mssql MSSQL) Delete(ctx context.Context, assetClass string) error {
+
+	if assetClass == constants.AssetDerivative {
+		return mssql.exec(ctx, constants.DeleteDerivative)
+	}
+	return mssql.exec(ctx, constants.DeleteEquity)
+}
+
+func (mssql MSSQL) UpsertEquity(ctx context.Context, target persistance.Target, scrips []entities.EquityScrip) error {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	proc := mssql.procs.EQInsert
+	if target == persistance.Staging {
+		proc = mssql.procs.StagingEQ
+	}
+	for _, eq := range scrips {
+		if qErr := ExecProcedure(ctx, db, proc, persistance.EquityValues(eq)); qErr != nil {
+			log.Error().Stack().Str("Procedure", proc.Name).Str("Token", eq.TokenMktID).Err(qErr).Msg("Error in updating AMX ScripMaster")
+			return fmt.Errorf("token %s: %w", eq.TokenMktID, qErr)
+		}
+	}
+	return nil
+}
+
+func (mssql MSSQL) UpsertDerivative(ctx context.Context, target persistance.Target, scrips []entities.DerivativeScrip) error {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	proc := mssql.procs.DervInsert
+	if target == persistance.Staging {
+		proc = mssql.procs.StagingDerv
+	}
+	for _, derv := range scrips {
+		if qErr := ExecProcedure(ctx, db, proc, persistance.DerivativeValues(derv)); qErr != nil {
+			log.Error().Stack().Str("Procedure", proc.Name).Str("Token", derv.TokenMktID).Err(qErr).Msg("Error in updating AMX ScripMaster")
+			return fmt.Errorf("token %s: %w", derv.TokenMktID, qErr)
+		}
+	}
+	return nil
+}
+
+func (mssql MSSQL) PrepareBulk(ctx context.Context) error {
+
+	if mssql.procs.Bulk.Prepare == "" {
+		return nil
+	}
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, qErr := db.ExecContext(ctx, mssql.procs.Bulk.Prepare); qErr != nil {
+		log.Error().Str("Query", mssql.procs.Bulk.Prepare).Err(qErr).Msg("Error in preparing bulk table")
+		return qErr
+	}
+	return nil
+}
+
+// BulkDerivative copies the contracts into the bulk table in batches of
+// BatchSize rows, Parallelism batches at a time.
+func (mssql MSSQL) BulkDerivative(ctx context.Context, scrips []entities.DerivativeScrip) (int64, error) {
+
+	rows := make([][]interface{}, 0, len(scrips))
+	for _, derv := range scrips {
+		row, err := mssql.procs.Bulk.Row(persistance.DerivativeValues(derv))
+		if err != nil {
+			return 0, fmt.Errorf("token %s: %w", derv.TokenMktID, err)
+		}
+		rows = append(rows, row)
+	}
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	copied, cErr := BulkCopy(ctx, db, mssql.procs.Bulk, rows, mssql.BatchSize, mssql.Parallelism)
+	if cErr != nil {
+		log.Error().Str("Table", mssql.procs.Bulk.Table).Int64("Copied", copied).Err(cErr).Msg("Error in bulk copy")
+	}
+	return copied, cErr
+}
+
+func (mssql MSSQL) MergeBulk(ctx context.Context, target persistance.Target, runID string) error {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	proc := mssql.procs.BulkMerge
+	if target == persistance.Staging {
+		proc = mssql.procs.BulkMergeStaging
+	}
+	if qErr := ExecProcedure(ctx, db, proc, runValues(runID, time.Time{})); qErr != nil {
+		log.Error().Str("Procedure", proc.Name).Err(qErr).Msg("Error in merging bulk table")
+		return qErr
+	}
+	return nil
+}
+
+func (mssql MSSQL) PrepareStaging(ctx context.Context) error {
+	return mssql.exec(ctx, constants.PrepareStaging)
+}
+
+func (mssql MSSQL) StagedCounts(ctx context.Context) (map[string]int, error) {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, qErr := db.QueryContext(ctx, mssql.queries[constants.StagingCountQuery])
+	if qErr != nil {
+		return nil, qErr
+	}
+	defer rows.Close()
+
+	return scanCounts(rows)
+}
+
+// SwapStaging switches the shadow tables in with a single transaction.
+func (mssql MSSQL) SwapStaging(ctx context.Context) error {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, txErr := db.BeginTx(ctx, nil)
+	if txErr != nil {
+		return txErr
+	}
+
+	sQuery := mssql.queries[constants.SwapStaging]
+	if _, qErr := tx.ExecContext(ctx, sQuery); qErr != nil {
+		tx.Rollback()
+		log.Error().Str("Query", sQuery).Err(qErr).Msg("Error in swapping staged scrip master")
+		return qErr
+	}
+	return tx.Commit()
+}
+
+func (mssql MSSQL) RefreshMarketCap(ctx context.Context) error {
+	return mssql.exec(ctx, constants.MarketCapQuery)
+}
+
+// UpdateStockIDs links the stock ids to the scrips with the same ISIN, one
+// procedure call per id. An id is updated when its call succeeded, a
+// failing call is logged and counted as failed.
+func (mssql MSSQL) UpdateStockIDs(ctx context.Context, ids []persistance.StockID) (persistance.StockIDUpdate, error) {
+
+	var result persistance.StockIDUpdate
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	for _, id := range ids {
+		qErr := ExecProcedure(ctx, db, mssql.procs.StockID, map[string]string{"sid": id.SID, "isin": id.ISIN})
+		if qErr != nil {
+			log.Error().Str("Procedure", mssql.procs.StockID.Name).Str("ISIN", id.ISIN).Err(qErr).Msg("Error In Stock Id Updation")
+			result.Failed++
+			continue
+		}
+		result.Updated++
+	}
+	return result, nil
+}
+
+func (mssql MSSQL) Snapshot(ctx context.Context) ([]diff.Record, error) {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sQuery := mssql.queries[constants.MasterSnapshot]
+	rows, qErr := db.QueryContext(ctx, sQuery)
+	if qErr != nil {
+		log.Error().Str("Query", sQuery).Err(qErr).Msg("Error in reading the live scrip master")
+		return nil, qErr
+	}
+	defer rows.Close()
+
+	var records []diff.Record
+	for rows.Next() {
+		var token, segmentID, symbol, lot, tick, freeze, isin sql.NullString
+		if err := rows.Scan(&token, &segmentID, &symbol, &lot, &tick, &freeze, &isin); err != nil {
+			return nil, err
+		}
+		records = append(records, diff.Record{
+			TokenMktID: token.String,
+			Segment:    segmentID.String,
+			Symbol:     symbol.String,
+			LotSize:    lot.String,
+			TickSize:   tick.String,
+			FreezeQty:  freeze.String,
+			ISIN:       isin.String,
+		})
+	}
+	return records, rows.Err()
+}
+
+// ReadMaster reads the live master as export rows. The export query has to
+// alias its columns to the export column names.
+func (mssql MSSQL) ReadMaster(ctx context.Context) ([]export.Row, error) {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sQuery := mssql.queries[constants.ExportQuery]
+	rows, qErr := db.QueryContext(ctx, sQuery)
+	if qErr != nil {
+		log.Error().Str("Query", sQuery).Err(qErr).Msg("Error in reading the scrip master")
+		return nil, qErr
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var master []export.Row
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(export.Row, len(columns)+1)
+		for i, column := range columns {
+			row[column] = values[i].String
+		}
+		master = append(master, row)
+	}
+	return master, rows.Err()
+}
+
+func (mssql MSSQL) Quarantine(ctx context.Context, records []quarantine.Record) error {
+
+	db, err := mssql.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		if qErr := ExecProcedure(ctx, db, mssql.procs.Quarantine, r.Values()); qErr != nil {
+			log.Error().Str("Procedure", mssql.procs.Quarantine.Name).Str("Segment", r.Segment).Err(qErr).Msg("Error in quarantining rejected record")
+			return qErr
+		}
+	}
+	return nil
+}
+
+// Flush does nothing, every procedure call commits.
+func (mssql MSSQL) Flush(ctx context.Context) error {
+	return nil
+}
+
+func scanCounts(rows *sql.Rows) (map[string]int, error) {
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var segmentID string
+		var count int
+		if err := rows.Scan(&segmentID, &count); err != nil {
+			return nil, err
+		}
+		counts[segmentID] = count
+	}
+	return counts, rows.Err()
+}
+
+func runValues(runID string, runTime time.Time) map[string]string {
+
+	return map[string]string{
+		"runId":   runID,
+		"runTime": runTime.Format(constants.RunTimeFormat),
+	}
+}
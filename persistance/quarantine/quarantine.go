@@ -0,0 +1,99 @@
+package quarantine
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Record is a scrip the parsers rejected, kept as it was received.
+type Record struct {
+	RunID   string          `json:"runId"`
+	Segment string          `json:"segment"`
+	Reason  string          `json:"reason"`
+	Error   string          `json:"error,omitempty"`
+	Data    json.RawMessage `json:"record"`
+}
+
+// Values returns the fields of the record for the quarantine procedure.
+func (r Record) Values() map[string]string {
+
+	return map[string]string{
+		"runId":   r.RunID,
+		"segment": r.Segment,
+		"reason":  r.Reason,
+		"error":   r.Error,
+		"record":  string(r.Data),
+	}
+}
+
+// Writer writes records to a file as JSON lines. The file appears under
+// its name once the writer is closed.
+type Writer struct {
+	path string
+	f    *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+}
+
+func NewWriter(path string) (*Writer, error) {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	return &Writer{path: path, f: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (w *Writer) Write(records []Record) error {
+
+	for _, r := range records {
+		if !json.Valid(r.Data) {
+			r.Data, _ = json.Marshal(string(r.Data))
+		}
+		if err := w.enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes the records and moves the file into place.
+func (w *Writer) Close() error {
+
+	if err := w.w.Flush(); err != nil {
+		w.f.Close()
+		return err
+	}
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(w.f.Name(), w.path)
+}
+
+// Abort drops the records written so far.
+func (w *Writer) Abort() {
+
+	w.f.Close()
+	os.Remove(w.f.Name())
+}
+
+// WriteFile writes the records to path as JSON lines.
+func WriteFile(path string, records []Record) error {
+
+	w, err := NewWriter(path)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(records); err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,108 @@
+package persistance
+
+import (
+	"strconv"
+
+	"main.go/diff"
+	"main.go/entities"
+	helper "main.go/helper"
+)
+
+// EquityValues returns the named values of an equity scrip that the
+// eqDataInsertion parameters are mapped onto.
+func EquityValues(eq entities.EquityScrip) map[string]string {
+
+	values := scripValues(eq.Scrip, eq.Divider, eq.Precision)
+	values["tokenMktId"] = eq.TokenMktID
+	values["segmentId"] = eq.SegmentID
+	values["assetClass"] = eq.AssetClass
+	values["expDate"] = eq.ExpDate
+	values["maturityDate"] = maturity(eq.Maturity)
+	values["details"] = eq.Details
+	values["priceNum"] = "1"
+	values["priceDen"] = "1"
+	return values
+}
+
+// DerivativeValues returns the named values of a derivative contract that
+// the dervDataInsertion parameters are mapped onto.
+func DerivativeValues(derv entities.DerivativeScrip) map[string]string {
+
+	values := scripValues(derv.Scrip, derv.Divider, derv.Precision)
+	values["tokenMktId"] = derv.TokenMktID
+	values["segmentId"] = derv.SegmentID
+	values["assetClass"] = derv.AssetClass
+	values["expDate"] = derv.ExpDate
+	values["maturityDate"] = maturity(derv.Maturity)
+	values["details"] = derv.Details
+	values["priceNum"] = derv.PriceNumer
+	values["priceDen"] = derv.PriceDenom
+	return values
+}
+
+func scripValues(s entities.Scrip, divider, precision string) map[string]string {
+
+	return map[string]string{
+		"symbol":              s.Symbol,
+		"symbolName":          s.SymbolName,
+		"trdSymbol":           s.TrdSymbol,
+		"series":              s.Series,
+		"instrumentType":      s.InstrumentType,
+		"marketType":          s.MarketType,
+		"securityDesc":        s.SecurityDesc,
+		"isinCode":            s.IsinCode,
+		"assetToken":          s.AssetToken,
+		"expiryDate":          s.ExpiryDate,
+		"optionType":          s.OptionType,
+		"faceValue":           s.FaceValue,
+		"qtyUnits":            s.QtyUnits,
+		"deliveryUnit":        s.DeliveryUnit,
+		"priceQuotFactor":     s.PriceQuotFactor,
+		"issueStartDate":      s.IssueStartDate,
+		"divider":             divider,
+		"precision":           precision,
+		"priceTick":           helper.SetPrecision(s.PriceTick, divider, precision),
+		"freezePercent":       helper.GetFreezepercentage(s.FreezePercent, divider, precision),
+		"minimumLot":          itoa(s.MinimumLot),
+		"regularLot":          itoa(s.RegularLot),
+		"lowPriceRange":       s.LowPriceRange.String(),
+		"highPriceRange":      s.HighPriceRange.String(),
+		"strikePrice":         s.StrikePrice.String(),
+		"priceQuotUnit":       itoa(s.PriceQuotUnit),
+		"maxSingleTransQty":   itoa(s.MaxSingleTransQty),
+		"maxSingleTransValue": itoa(s.MaxSingleTransValue),
+		"openInterest":        itoa(s.OpenInterest),
+		"totalValueTraded":    itoa(s.TotalValueTraded),
+		"basePrice":           s.BasePrice.String(),
+		"issueCapital":        itoa(s.IssueCapital),
+		"normalMarketAllowed": itoa(s.NormalMarketAllowed),
+	}
+}
+
+// maturity returns the formatted maturity date, "-" when there is none.
+func maturity(date string) string {
+
+	if date == "" {
+		return "-"
+	}
+	return date
+}
+
+func itoa(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+// SnapshotRecord returns the diff fields of a master row, with the market
+// segment id as segment.
+func SnapshotRecord(values map[string]string) diff.Record {
+
+	return diff.Record{
+		TokenMktID: values["tokenMktId"],
+		Segment:    values["segmentId"],
+		Symbol:     values["symbolName"],
+		LotSize:    values["minimumLot"],
+		TickSize:   values["priceTick"],
+		FreezeQty:  values["freezePercent"],
+		ISIN:       values["isinCode"],
+	}
+}
@@ -0,0 +1,343 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"main.go/dates"
+)
+
+// Actions of a rule.
+const (
+	Include = "include"
+	Skip    = "skip"
+)
+
+// Predicate is a condition on the fields of a scrip. Exactly one of all,
+// any, not and field is set, and a field predicate has exactly one
+// operator:
+//
+//	eq      the field equals the value
+//	in      the field is one of the values
+//	in_list the field is one of the values of a named list
+//	prefix  the field starts with one of the values
+//	empty   the field is (true) or is not (false) empty
+//	expired the field is an expiry date of the scrip's segment that has
+//	        (true) or has not (false) passed, see dates.Calendar.Expired.
+//	        An empty field is never expired and a field that is not a
+//	        number always is
+type Predicate struct {
+	All []Predicate `mapstructure:"all"`
+	Any []Predicate `mapstructure:"any"`
+	Not *Predicate  `mapstructure:"not"`
+
+	Field   string   `mapstructure:"field"`
+	Eq      *string  `mapstructure:"eq"`
+	In      []string `mapstructure:"in"`
+	InList  string   `mapstructure:"in_list"`
+	Prefix  []string `mapstructure:"prefix"`
+	Empty   *bool    `mapstructure:"empty"`
+	Expired *bool    `mapstructure:"expired"`
+}
+
+// Rule applies its action to the scrips matching When. The reason labels
+// the decision in logs and skip counts.
+type Rule struct {
+	Reason string    `mapstructure:"reason"`
+	Action string    `mapstructure:"action"`
+	When   Predicate `mapstructure:"when"`
+}
+
+// RuleSet is evaluated top down, the first matching rule decides. Default
+// decides when no rule matches, its When is ignored.
+type RuleSet struct {
+	Default Rule   `mapstructure:"default"`
+	Rules   []Rule `mapstructure:"rules"`
+}
+
+// Decision is the outcome of evaluating a rule set for one scrip.
+type Decision struct {
+	Include bool
+	Reason  string
+}
+
+// Fields returns the value of a field of the scrip being evaluated.
+type Fields func(name string) (string, bool)
+
+type matcher func(fields Fields, now time.Time) bool
+
+type compiledRule struct {
+	decision Decision
+	match    matcher
+}
+
+type compiledSet struct {
+	rules    []compiledRule
+	fallback Decision
+}
+
+// Engine holds the compiled rule sets. Dates reads the expiry dates, by
+// the segment field of the scrip.
+type Engine struct {
+	sets  map[string]*compiledSet
+	Now   func() time.Time
+	Dates *dates.Calendar
+}
+
+// Load reads the named lists and rule sets from config and compiles them.
+// Every field used by a predicate has to be one of fields, and every set in
+// required has to be configured.
+func Load(config *viper.Viper, fields []string, required ...string) (*Engine, error) {
+
+	var lists map[string][]string
+	if err := config.UnmarshalKey("lists", &lists); err != nil {
+		return nil, fmt.Errorf("rules: lists: %w", err)
+	}
+	var sets map[string]RuleSet
+	if err := config.UnmarshalKey("sets", &sets); err != nil {
+		return nil, fmt.Errorf("rules: sets: %w", err)
+	}
+	return New(lists, sets, fields, required...)
+}
+
+// New compiles the rule sets.
+func New(lists map[string][]string, sets map[string]RuleSet, fields []string, required ...string) (*Engine, error) {
+
+	e := &Engine{sets: make(map[string]*compiledSet), Now: time.Now, Dates: dates.New(nil, 0)}
+	c := &compiler{engine: e, lists: make(map[string]map[string]bool), fields: make(map[string]bool)}
+	for name, values := range lists {
+		c.lists[strings.ToLower(name)] = toSet(values)
+	}
+	for _, f := range fields {
+		c.fields[f] = true
+	}
+
+	for name, set := range sets {
+		compiled, err := c.set(set)
+		if err != nil {
+			return nil, fmt.Errorf("rules: %s: %w", name, err)
+		}
+		e.sets[strings.ToLower(name)] = compiled
+	}
+	for _, name := range required {
+		if _, ok := e.sets[strings.ToLower(name)]; !ok {
+			return nil, fmt.Errorf("rules: rule set %s not configured", name)
+		}
+	}
+	return e, nil
+}
+
+// Evaluate decides whether a scrip is included by the named rule set. It
+// fails for a rule set that is not configured.
+func (e *Engine) Evaluate(set string, fields Fields) (Decision, error) {
+
+	compiled, ok := e.sets[strings.ToLower(set)]
+	if !ok {
+		return Decision{}, fmt.Errorf("rules: rule set %s not configured", set)
+	}
+
+	now := e.Now()
+	for _, rule := range compiled.rules {
+		if rule.match(fields, now) {
+			return rule.decision, nil
+		}
+	}
+	return compiled.fallback, nil
+}
+
+type compiler struct {
+	engine *Engine
+	lists  map[string]map[string]bool
+	fields map[string]bool
+}
+
+func (c *compiler) set(set RuleSet) (*compiledSet, error) {
+
+	fallback, err := decision(set.Default)
+	if err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+
+	compiled := &compiledSet{fallback: fallback}
+	for index, rule := range set.Rules {
+		d, err := decision(rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", index+1, err)
+		}
+		if d.Reason == "" {
+			return nil, fmt.Errorf("rule %d: reason not configured", index+1)
+		}
+		match, err := c.predicate(rule.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", index+1, rule.Reason, err)
+		}
+		compiled.rules = append(compiled.rules, compiledRule{decision: d, match: match})
+	}
+	return compiled, nil
+}
+
+func decision(rule Rule) (Decision, error) {
+
+	switch strings.ToLower(rule.Action) {
+	case Include:
+		return Decision{Include: true, Reason: rule.Reason}, nil
+	case Skip:
+		return Decision{Include: false, Reason: rule.Reason}, nil
+	default:
+		return Decision{}, fmt.Errorf("unknown action %q, expected include or skip", rule.Action)
+	}
+}
+
+func (c *compiler) predicate(p Predicate) (matcher, error) {
+
+	kinds := 0
+	for _, set := range []bool{p.All != nil, p.Any != nil, p.Not != nil, p.Field != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("predicate needs exactly one of all, any, not or field")
+	}
+
+	switch {
+	case p.All != nil:
+		matchers, err := c.predicates(p.All)
+		if err != nil {
+			return nil, err
+		}
+		return func(fields Fields, now time.Time) bool {
+			for _, m := range matchers {
+				if !m(fields, now) {
+					return false
+				}
+			}
+			return true
+		}, nil
+
+	case p.Any != nil:
+		matchers, err := c.predicates(p.Any)
+		if err != nil {
+			return nil, err
+		}
+		return func(fields Fields, now time.Time) bool {
+			for _, m := range matchers {
+				if m(fields, now) {
+					return true
+				}
+			}
+			return false
+		}, nil
+
+	case p.Not != nil:
+		m, err := c.predicate(*p.Not)
+		if err != nil {
+			return nil, err
+		}
+		return func(fields Fields, now time.Time) bool {
+			return !m(fields, now)
+		}, nil
+	}
+
+	return c.field(p)
+}
+
+func (c *compiler) predicates(list []Predicate) ([]matcher, error) {
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("all and any need at least one predicate")
+	}
+	matchers := make([]matcher, 0, len(list))
+	for _, p := range list {
+		m, err := c.predicate(p)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func (c *compiler) field(p Predicate) (matcher, error) {
+
+	if !c.fields[p.Field] {
+		return nil, fmt.Errorf("unknown field %q", p.Field)
+	}
+	name := p.Field
+	value := func(fields Fields) string {
+		v, _ := fields(name)
+		return v
+	}
+
+	var match matcher
+	ops := 0
+	if p.Eq != nil {
+		ops++
+		eq := *p.Eq
+		match = func(fields Fields, _ time.Time) bool {
+			return value(fields) == eq
+		}
+	}
+	if p.In != nil || p.InList != "" {
+		ops++
+		set := toSet(p.In)
+		if p.InList != "" {
+			list, ok := c.lists[strings.ToLower(p.InList)]
+			if !ok {
+				return nil, fmt.Errorf("unknown list %q", p.InList)
+			}
+			if p.In != nil {
+				return nil, fmt.Errorf("field %s: use either in or in_list", name)
+			}
+			set = list
+		}
+		match = func(fields Fields, _ time.Time) bool {
+			return set[value(fields)]
+		}
+	}
+	if p.Prefix != nil {
+		ops++
+		prefixes := p.Prefix
+		match = func(fields Fields, _ time.Time) bool {
+			v := value(fields)
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(v, prefix) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	if p.Empty != nil {
+		ops++
+		empty := *p.Empty
+		match = func(fields Fields, _ time.Time) bool {
+			return (value(fields) == "") == empty
+		}
+	}
+	if p.Expired != nil {
+		ops++
+		expired := *p.Expired
+		engine := c.engine
+		match = func(fields Fields, now time.Time) bool {
+			segment, _ := fields("segment")
+			return engine.Dates.Expired(segment, value(fields), now) == expired
+		}
+	}
+
+	if ops != 1 {
+		return nil, fmt.Errorf("field %s needs exactly one of eq, in, in_list, prefix, empty or expired", name)
+	}
+	return match, nil
+}
+
+func toSet(values []string) map[string]bool {
+
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,340 @@
+package rules_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"main.go/dates"
+	"main.go/rules"
+)
+
+// 27 Jun 2024 15:30 IST, in seconds since the 1980 epoch of nse_fo
+const nseExpiry = "1403969400"
+
+var testFields = []string{"segment", "symbol", "series", "instrumentType", "expiryDate"}
+
+func fields(values map[string]string) rules.Fields {
+
+	return func(name string) (string, bool) {
+		v, ok := values[name]
+		return v, ok
+	}
+}
+
+func str(s string) *string { return &s }
+
+func flag(b bool) *bool { return &b }
+
+// engine returns an engine with one set "test" that includes the scrips
+// matching when with reason "hit" and skips the others with reason "miss".
+func engine(t *testing.T, when rules.Predicate) *rules.Engine {
+
+	t.Helper()
+	sets := map[string]rules.RuleSet{"test": {
+		Default: rules.Rule{Action: rules.Skip, Reason: "miss"},
+		Rules:   []rules.Rule{{Action: rules.Include, Reason: "hit", When: when}},
+	}}
+	lists := map[string][]string{"nse_series": {"EQ", "BE"}}
+	e, err := rules.New(lists, sets, testFields, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	epoch, err := dates.ParseEpoch("1980-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Dates = dates.New(map[string]time.Time{"nse_fo": epoch}, 0)
+	return e
+}
+
+func TestPredicates(t *testing.T) {
+
+	eq := func(field, value string) rules.Predicate { return rules.Predicate{Field: field, Eq: str(value)} }
+	expired := rules.Predicate{Field: "expiryDate", Expired: flag(true)}
+	// the end of the expiry day in exchange time
+	endOfExpiry := time.Date(2024, 6, 28, 0, 0, 0, 0, dates.Exchange)
+
+	tests := []struct {
+		name   string
+		when   rules.Predicate
+		fields map[string]string
+		now    time.Time
+		want   bool
+	}{
+		{name: "eq", when: eq("series", "EQ"), fields: map[string]string{"series": "EQ"}, want: true},
+		{name: "eq differs", when: eq("series", "EQ"), fields: map[string]string{"series": "eq"}, want: false},
+		{name: "in", when: rules.Predicate{Field: "series", In: []string{"A", "SM"}}, fields: map[string]string{"series": "SM"}, want: true},
+		{name: "in missing", when: rules.Predicate{Field: "series", In: []string{"A", "SM"}}, fields: map[string]string{"series": "B"}, want: false},
+		{name: "in_list", when: rules.Predicate{Field: "series", InList: "nse_series"}, fields: map[string]string{"series": "BE"}, want: true},
+		{name: "in_list name case", when: rules.Predicate{Field: "series", InList: "NSE_Series"}, fields: map[string]string{"series": "BE"}, want: true},
+		{name: "in_list missing", when: rules.Predicate{Field: "series", InList: "nse_series"}, fields: map[string]string{"series": "ZZ"}, want: false},
+		{name: "prefix", when: rules.Predicate{Field: "instrumentType", Prefix: []string{"FUT", "OPT"}}, fields: map[string]string{"instrumentType": "OPTIDX"}, want: true},
+		{name: "prefix missing", when: rules.Predicate{Field: "instrumentType", Prefix: []string{"FUT", "OPT"}}, fields: map[string]string{"instrumentType": "COMDTY"}, want: false},
+		{name: "empty", when: rules.Predicate{Field: "symbol", Empty: flag(true)}, fields: map[string]string{"symbol": ""}, want: true},
+		{name: "empty unset field", when: rules.Predicate{Field: "symbol", Empty: flag(true)}, fields: map[string]string{}, want: true},
+		{name: "not empty", when: rules.Predicate{Field: "symbol", Empty: flag(false)}, fields: map[string]string{"symbol": "500325"}, want: true},
+		{name: "expired after the expiry day", when: expired, fields: map[string]string{"segment": "nse_fo", "expiryDate": nseExpiry}, now: endOfExpiry, want: true},
+		{name: "not expired on the expiry day", when: expired, fields: map[string]string{"segment": "nse_fo", "expiryDate": nseExpiry}, now: endOfExpiry.Add(-time.Second), want: false},
+		{name: "empty expiry never expires", when: expired, fields: map[string]string{"segment": "nse_fo", "expiryDate": ""}, now: endOfExpiry, want: false},
+		{name: "invalid expiry always expired", when: expired, fields: map[string]string{"segment": "nse_fo", "expiryDate": "soon"}, now: endOfExpiry, want: true},
+		{name: "not expired", when: rules.Predicate{Field: "expiryDate", Expired: flag(false)}, fields: map[string]string{"segment": "nse_fo", "expiryDate": nseExpiry}, now: endOfExpiry.Add(-time.Second), want: true},
+		{name: "all", when: rules.Predicate{All: []rules.Predicate{eq("segment", "nse_cm"), eq("series", "EQ")}}, fields: map[string]string{"segment": "nse_cm", "series": "EQ"}, want: true},
+		{name: "all one fails", when: rules.Predicate{All: []rules.Predicate{eq("segment", "nse_cm"), eq("series", "EQ")}}, fields: map[string]string{"segment": "bse_cm", "series": "EQ"}, want: false},
+		{name: "any", when: rules.Predicate{Any: []rules.Predicate{eq("series", "A"), eq("series", "EQ")}}, fields: map[string]string{"series": "EQ"}, want: true},
+		{name: "any none", when: rules.Predicate{Any: []rules.Predicate{eq("series", "A"), eq("series", "EQ")}}, fields: map[string]string{"series": "BE"}, want: false},
+		{name: "not", when: rules.Predicate{Not: &rules.Predicate{Field: "series", InList: "nse_series"}}, fields: map[string]string{"series": "ZZ"}, want: true},
+		{name: "not matching", when: rules.Predicate{Not: &rules.Predicate{Field: "series", InList: "nse_series"}}, fields: map[string]string{"series": "EQ"}, want: false},
+		{name: "nested", when: rules.Predicate{All: []rules.Predicate{
+			eq("segment", "nse_fo"),
+			{Any: []rules.Predicate{{Not: &expired}, {Field: "instrumentType", Prefix: []string{"FUT"}}}},
+		}}, fields: map[string]string{"segment": "nse_fo", "instrumentType": "OPTIDX", "expiryDate": nseExpiry}, now: endOfExpiry, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engine(t, tt.when)
+			e.Now = func() time.Time { return tt.now }
+
+			got, err := e.Evaluate("test", fields(tt.fields))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Include != tt.want {
+				t.Errorf("Evaluate() = %+v, want include %v", got, tt.want)
+			}
+			if reason := map[bool]string{true: "hit", false: "miss"}[tt.want]; got.Reason != reason {
+				t.Errorf("reason %q, want %q", got.Reason, reason)
+			}
+		})
+	}
+}
+
+func TestFirstMatchDecides(t *testing.T) {
+
+	sets := map[string]rules.RuleSet{"test": {
+		Default: rules.Rule{Action: rules.Include, Reason: "valid"},
+		Rules: []rules.Rule{
+			{Action: rules.Skip, Reason: "sp_remark", When: rules.Predicate{Field: "series", Eq: str("SP")}},
+			{Action: rules.Skip, Reason: "empty_symbol", When: rules.Predicate{Field: "symbol", Empty: flag(true)}},
+		},
+	}}
+	e, err := rules.New(nil, sets, testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		fields map[string]string
+		want   rules.Decision
+	}{
+		{map[string]string{"series": "SP", "symbol": ""}, rules.Decision{Include: false, Reason: "sp_remark"}},
+		{map[string]string{"series": "EQ", "symbol": ""}, rules.Decision{Include: false, Reason: "empty_symbol"}},
+		{map[string]string{"series": "EQ", "symbol": "1"}, rules.Decision{Include: true, Reason: "valid"}},
+	}
+	for _, tt := range tests {
+		got, err := e.Evaluate("TEST", fields(tt.fields))
+		if err != nil || got != tt.want {
+			t.Errorf("Evaluate(%v) = %+v, %v, want %+v", tt.fields, got, err, tt.want)
+		}
+	}
+}
+
+func TestEvaluateUnknownSet(t *testing.T) {
+
+	e := engine(t, rules.Predicate{Field: "symbol", Empty: flag(false)})
+	if got, err := e.Evaluate("equity", fields(map[string]string{"symbol": "1"})); err == nil {
+		t.Errorf("Evaluate() = %+v for an unknown set, want an error", got)
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+
+	rule := func(when rules.Predicate) map[string]rules.RuleSet {
+		return map[string]rules.RuleSet{"test": {
+			Default: rules.Rule{Action: rules.Include},
+			Rules:   []rules.Rule{{Action: rules.Skip, Reason: "r", When: when}},
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		sets     map[string]rules.RuleSet
+		required []string
+		wantErr  string
+	}{
+		{"unknown field", rule(rules.Predicate{Field: "colour", Eq: str("red")}), nil, "unknown field"},
+		{"unknown list", rule(rules.Predicate{Field: "series", InList: "bse_series"}), nil, "unknown list"},
+		{"in and in_list", rule(rules.Predicate{Field: "series", In: []string{"A"}, InList: "nse_series"}), nil, "either in or in_list"},
+		{"two operators", rule(rules.Predicate{Field: "series", Eq: str("A"), Empty: flag(true)}), nil, "exactly one of eq"},
+		{"no operator", rule(rules.Predicate{Field: "series"}), nil, "exactly one of eq"},
+		{"two kinds", rule(rules.Predicate{Field: "series", Eq: str("A"), Not: &rules.Predicate{Field: "symbol", Empty: flag(true)}}), nil, "exactly one of all"},
+		{"empty all", rule(rules.Predicate{All: []rules.Predicate{}}), nil, "at least one predicate"},
+		{"unknown action", map[string]rules.RuleSet{"test": {Default: rules.Rule{Action: "drop"}}}, nil, "unknown action"},
+		{"missing reason", map[string]rules.RuleSet{"test": {
+			Default: rules.Rule{Action: rules.Include},
+			Rules:   []rules.Rule{{Action: rules.Skip, When: rules.Predicate{Field: "symbol", Empty: flag(true)}}},
+		}}, nil, "reason not configured"},
+		{"required set missing", rule(rules.Predicate{Field: "symbol", Empty: flag(true)}), []string{"equity"}, "equity not configured"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rules.New(map[string][]string{"nse_series": {"EQ"}}, tt.sets, testFields, tt.required...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// baselineEquitySkip is the hand-written equity filter the rules replaced.
+func baselineEquitySkip(segment, symbol, series, remarks string) bool {
+
+	nse := map[string]bool{"EQ": true, "BE": true, "BZ": true, "GB": true, "E1": true, "RR": true, "IV": true, "SM": true, "ST": true}
+	bse := map[string]bool{"A": true, "SM": true, "ST": true, "RR": true}
+
+	if remarks == "SP" || symbol == "" {
+		return true
+	}
+	if segment == "nse_cm" && !nse[series] {
+		return true
+	}
+	if segment == "bse_cm" && !strings.HasPrefix(symbol, "7") &&
+		!strings.HasPrefix(symbol, "5") && (!strings.HasPrefix(symbol, "8") && !bse[series]) {
+		return true
+	}
+	return false
+}
+
+func TestEquityRulesMatchBaseline(t *testing.T) {
+
+	config := viper.New()
+	config.SetConfigFile(filepath.Join("..", "resources", "configs", "rules.yaml"))
+	if err := config.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := rules.Load(config, []string{"segment", "symbol", "series", "remarksText", "instrumentType", "expiryDate"}, "equity", "derivative")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, segment := range []string{"nse_cm", "bse_cm"} {
+		for _, symbol := range []string{"", "500325", "532540", "700001", "800123", "890001", "100234", "957", "INFY", "5", "7", "8"} {
+			for _, series := range []string{"", "A", "B", "EQ", "SM", "ST", "RR", "T", "Z", "X"} {
+				for _, remarks := range []string{"", "SP", "NS"} {
+					name := fmt.Sprintf("%s/%q/%q/%q", segment, symbol, series, remarks)
+					got, err := e.Evaluate("equity", fields(map[string]string{"segment": segment, "symbol": symbol, "series": series, "remarksText": remarks}))
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					if want := !baselineEquitySkip(segment, symbol, series, remarks); got.Include != want {
+						t.Errorf("%s: include %v (%s), baseline %v", name, got.Include, got.Reason, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+// baselineDerivativeSkip is the hand-written derivative filter the rules
+// replaced, expiry is in seconds since the Unix epoch.
+func baselineDerivativeSkip(instrument, expiry string, now time.Time) bool {
+
+	if strings.HasPrefix(instrument, "FUT") || strings.HasPrefix(instrument, "OPT") {
+		if expiry == "" {
+			return true
+		}
+		epoch, _ := strconv.ParseInt(expiry, 10, 64)
+		return epoch < now.Unix()
+	}
+	return instrument != "COMDTY" && instrument != "UNDCUR"
+}
+
+func TestRulesMatchBaselineOnFixtures(t *testing.T) {
+
+	config := viper.New()
+	config.SetConfigFile(filepath.Join("..", "resources", "configs", "rules.yaml"))
+	if err := config.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	ruleFields := []string{"segment", "symbol", "series", "remarksText", "instrumentType", "expiryDate"}
+	e, err := rules.Load(config, ruleFields, "equity", "derivative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, dates.Exchange)
+	e.Now = func() time.Time { return now }
+	// the baseline read every expiry from the Unix epoch
+	e.Dates = dates.New(nil, 0)
+
+	records := make(map[string][]map[string]interface{})
+	for _, segment := range []string{"nse_cm", "bse_cm", "nse_fo", "mcx_fo"} {
+		data, err := os.ReadFile(filepath.Join("..", "amx", "amxtest", "testdata", segment+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page []map[string]interface{}
+		if err := json.Unmarshal(data, &page); err != nil {
+			t.Fatalf("%s: %v", segment, err)
+		}
+		records[segment] = page
+	}
+
+	// the cases the fixtures leave out
+	epoch := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+	records["bse_cm"] = append(records["bse_cm"],
+		map[string]interface{}{"symbol": "500001", "series": "Z"},
+		map[string]interface{}{"symbol": "700001", "series": ""},
+		map[string]interface{}{"symbol": "890001", "series": "X"},
+		map[string]interface{}{"symbol": "600001", "series": "SM"},
+		map[string]interface{}{"symbol": "600002", "series": "B"},
+		map[string]interface{}{"symbol": "", "series": "A"},
+		map[string]interface{}{"symbol": "500002", "series": "A", "remarksText": "SP"},
+	)
+	records["nse_cm"] = append(records["nse_cm"],
+		map[string]interface{}{"symbol": "", "series": "EQ"},
+		map[string]interface{}{"symbol": "1", "series": ""},
+	)
+	records["nse_fo"] = append(records["nse_fo"],
+		map[string]interface{}{"instrumentType": "OPTSTK", "expiryDate": epoch(now.AddDate(0, 0, -1))},
+		map[string]interface{}{"instrumentType": "OPTSTK", "expiryDate": epoch(now.AddDate(0, 0, 7))},
+		map[string]interface{}{"instrumentType": "OPTIDX", "expiryDate": ""},
+		map[string]interface{}{"instrumentType": "FUTIDX"},
+		map[string]interface{}{"instrumentType": "FUTIDX", "expiryDate": "soon"},
+		map[string]interface{}{"instrumentType": "UNDCUR"},
+		map[string]interface{}{"instrumentType": ""},
+	)
+
+	str := func(record map[string]interface{}, field string) string {
+		s, _ := record[field].(string)
+		return s
+	}
+	for segment, page := range records {
+		for _, record := range page {
+			values := map[string]string{"segment": segment}
+			for _, field := range ruleFields[1:] {
+				values[field] = str(record, field)
+			}
+
+			set, skip := "derivative", baselineDerivativeSkip(values["instrumentType"], values["expiryDate"], now)
+			if segment == "nse_cm" || segment == "bse_cm" {
+				set, skip = "equity", baselineEquitySkip(segment, values["symbol"], values["series"], values["remarksText"])
+			}
+			got, err := e.Evaluate(set, fields(values))
+			if err != nil {
+				t.Fatalf("%s %v: %v", segment, values, err)
+			}
+			if got.Include == skip {
+				t.Errorf("%s %v: include %v (%s), baseline %v", segment, values, got.Include, got.Reason, !skip)
+			}
+		}
+	}
+}
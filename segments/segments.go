@@ -0,0 +1,149 @@
+package segments
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+	"main.go/dates"
+)
+
+// Parser kinds of a segment.
+const (
+	Equity     = "equity"
+	Derivative = "derivative"
+	Commodity  = "commodity"
+)
+
+// Segment describes one AMX exchange segment of the scrip master.
+type Segment struct {
+	Code       string `mapstructure:"code"`
+	MarketID   string `mapstructure:"market_id"`
+	AssetClass string `mapstructure:"asset_class"`
+	Parser     string `mapstructure:"parser"`
+	Divider    string `mapstructure:"divider"`
+	Precision  string `mapstructure:"precision"`
+	Epoch      string `mapstructure:"epoch"`
+	Enabled    bool   `mapstructure:"enabled"`
+	Bulk       bool   `mapstructure:"bulk"`
+}
+
+// IsEquity reports whether the segment is parsed as cash market scrips.
+func (s Segment) IsEquity() bool {
+	return s.Parser == Equity
+}
+
+// Registry is the configured list of segments, in config order.
+type Registry struct {
+	segments []Segment
+	byCode   map[string]Segment
+	byID     map[string]Segment
+}
+
+// Load reads the segment registry from the list under key.
+func Load(config *viper.Viper, key string) (*Registry, error) {
+
+	var list []Segment
+	if err := config.UnmarshalKey(key, &list); err != nil {
+		return nil, fmt.Errorf("segments: %w", err)
+	}
+	return New(list)
+}
+
+// New validates the segments and builds the registry.
+func New(list []Segment) (*Registry, error) {
+
+	r := &Registry{byCode: make(map[string]Segment), byID: make(map[string]Segment)}
+	for _, s := range list {
+		switch {
+		case s.Code == "" || s.MarketID == "":
+			return nil, fmt.Errorf("segments: every segment needs a code and a market_id")
+		case s.Parser != Equity && s.Parser != Derivative && s.Parser != Commodity:
+			return nil, fmt.Errorf("segments: %s: unknown parser %q", s.Code, s.Parser)
+		case s.AssetClass == "":
+			return nil, fmt.Errorf("segments: %s: asset_class not configured", s.Code)
+		case s.Bulk && s.IsEquity():
+			return nil, fmt.Errorf("segments: %s: bulk load is only supported for derivative segments", s.Code)
+		}
+		if _, err := strconv.ParseInt(s.Divider, 10, 64); err != nil {
+			return nil, fmt.Errorf("segments: %s: invalid divider %q", s.Code, s.Divider)
+		}
+		if _, err := strconv.Atoi(s.Precision); err != nil {
+			return nil, fmt.Errorf("segments: %s: invalid precision %q", s.Code, s.Precision)
+		}
+		if _, err := dates.ParseEpoch(s.Epoch); err != nil {
+			return nil, fmt.Errorf("segments: %s: %w", s.Code, err)
+		}
+		if _, ok := r.byCode[s.Code]; ok {
+			return nil, fmt.Errorf("segments: %s configured twice", s.Code)
+		}
+		if other, ok := r.byID[s.MarketID]; ok {
+			return nil, fmt.Errorf("segments: %s and %s share market_id %s", other.Code, s.Code, s.MarketID)
+		}
+		r.segments = append(r.segments, s)
+		r.byCode[s.Code] = s
+		r.byID[s.MarketID] = s
+	}
+	return r, nil
+}
+
+// Enabled returns the segments to fetch, in config order.
+func (r *Registry) Enabled() []Segment {
+
+	var enabled []Segment
+	for _, s := range r.segments {
+		if s.Enabled {
+			enabled = append(enabled, s)
+		}
+	}
+	return enabled
+}
+
+// HasBulk reports whether an enabled segment is loaded through the bulk
+// table.
+func (r *Registry) HasBulk() bool {
+
+	for _, s := range r.Enabled() {
+		if s.Bulk {
+			return true
+		}
+	}
+	return false
+}
+
+// Get looks up a segment by its AMX exchange code.
+func (r *Registry) Get(code string) (Segment, bool) {
+
+	s, ok := r.byCode[code]
+	return s, ok
+}
+
+// ByMarketID looks up a segment by its numeric market segment id.
+func (r *Registry) ByMarketID(id string) (Segment, bool) {
+
+	s, ok := r.byID[id]
+	return s, ok
+}
+
+// Names maps the market segment id of every configured segment, enabled or
+// not, to its exchange code.
+func (r *Registry) Names() map[string]string {
+
+	names := make(map[string]string, len(r.segments))
+	for _, s := range r.segments {
+		names[s.MarketID] = s.Code
+	}
+	return names
+}
+
+// Epochs maps the code of every configured segment to the epoch of its
+// date fields.
+func (r *Registry) Epochs() map[string]time.Time {
+
+	epochs := make(map[string]time.Time, len(r.segments))
+	for _, s := range r.segments {
+		epochs[s.Code], _ = dates.ParseEpoch(s.Epoch)
+	}
+	return epochs
+}
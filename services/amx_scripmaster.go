@@ -1,14 +1,11 @@
 package services
 
 import (
-	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -17,577 +14,497 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
+	amxapi "main.go/amx"
+	"main.go/archive"
+	"main.go/commodity"
 	"main.go/constants"
+	"main.go/dates"
+	"main.go/entities"
 	helper "main.go/helper"
+	"main.go/mojo"
+	"main.go/persistance"
+	"main.go/persistance/memory"
 	"main.go/persistance/mssql"
+	"main.go/persistance/quarantine"
+	"main.go/rules"
+	"main.go/segments"
 )
 
-type Logger struct {
-	IsDBFailed     bool
-	IsAPIFailed    bool
-	FailureMessage string
-	Details        string
-	Url            string
+// ParsedSegment holds the scrips of one segment that passed the skip rules.
+type ParsedSegment struct {
+	Segment     string
+	Equity      []entities.EquityScrip
+	Derivatives []entities.DerivativeScrip
+	Count       int
+	SkipCount   int
+	Skipped     map[string]int
+	Rejected    []quarantine.Record
 }
 
-type AMXConfig struct {
-	AppConfig, UrlConfig, DBConfig                          *viper.Viper
-	MSSQLEntities                                           mssql.MSSQL
-	ISBackupDone                                            bool
-	vSegments, vNse_Series, vBse_Series, vIndex_Instruments []string
-	Log                                                     Logger
-}
+// Skip reasons of records rejected by the parsers rather than the rules.
+const (
+	ReasonDecodeError         = "decode_error"
+	ReasonInvalidContractSpec = "invalid_contract_spec"
+	ReasonRuleError           = "rule_error"
+)
 
-var wg sync.WaitGroup
+func (p *ParsedSegment) skip(runID, reason string, record json.RawMessage, err error) {
 
-func (amx *AMXConfig) Init() {
+	p.SkipCount++
+	if p.Skipped == nil {
+		p.Skipped = make(map[string]int)
+	}
+	p.Skipped[reason]++
 
-	segments := amx.AppConfig.GetString(constants.SegmentsAllowed)
-	nse_series := amx.AppConfig.GetString(constants.NseSeries)
-	bse_series := amx.AppConfig.GetString(constants.BseSeries)
-	index_instruments := amx.AppConfig.GetString(constants.IndexInstruments)
-	amx.vSegments = strings.Split(segments, ",")
-	amx.vNse_Series = strings.Split(nse_series, ",")
-	amx.vBse_Series = strings.Split(bse_series, ",")
-	amx.vIndex_Instruments = strings.Split(index_instruments, ",")
-	amx.MSSQLEntities = mssql.MSSQL{Server: amx.AppConfig.GetString(constants.Server), Database: amx.AppConfig.GetString(constants.Database), Port: amx.AppConfig.GetInt(constants.Port), User: amx.AppConfig.GetString(constants.User), Password: amx.AppConfig.GetString(constants.Password)}
+	rejected := quarantine.Record{RunID: runID, Segment: p.Segment, Reason: reason, Data: record}
+	if err != nil {
+		rejected.Error = err.Error()
+	}
+	p.Rejected = append(p.Rejected, rejected)
+}
 
+type AMXConfig struct {
+	AppConfig, UrlConfig, DBConfig, RulesConfig *viper.Viper
+	MSSQLEntities                               mssql.MSSQL
+	Store                                       persistance.Database
+	ISBackupDone                                bool
+	DryRun                                      bool
+	Force                                       bool
+	RunID                                       string
+	RunTime                                     time.Time
+	Now                                         func() time.Time // clock the filter rules compare expiries with, time.Now when nil
+	registry                                    *segments.Registry
+	dates                                       *dates.Calendar
+	rules                                       *rules.Engine
+	client                                      *amxapi.Client
+	mojo                                        *mojo.Client
+
+	mu       sync.Mutex
+	affected map[string]bool
 }
 
-func (amx *AMXConfig) Login() string {
-
-	url := amx.UrlConfig.GetString(amx.AppConfig.GetString(constants.Env) + "." + constants.GetLoginUrl)
-	client := http.Client{}
-	body := map[string]string{}
-	body["userid"] = amx.AppConfig.GetString(amx.AppConfig.GetString(constants.Env) + "." + constants.UserID)
-	body["passorpin"] = amx.AppConfig.GetString(amx.AppConfig.GetString(constants.Env) + "." + constants.UserPassword)
-	json_req, _ := json.Marshal(body)
-
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(json_req))
-	req.Header.Set("X-SourceID", "2")
-	req.Header.Set("X-Platform", "MSIL")
-	req.Header.Set("X-DeviceID", "MSIL-MW")
-	req.Header.Set("X-UserType", "1")
-	req.Header.Set("X-OperatingSystem", "Linux")
-	req.Header.Set("Content-Type", "application/json")
-
-	response, httpErr := client.Do(req)
-	if httpErr != nil {
-		log.Error().Stringer("Requesting Url : ", req.URL).RawJSON("Request : ", json_req).Interface("Headers : ", req.Header).Err(httpErr).Msg("AMX Login Failed")
-		amx.Log.IsAPIFailed = true
-		amx.Log.FailureMessage = httpErr.Error()
-		amx.Log.Details = "AMX login api has been failed"
-		amx.Log.Url = url
-		amx.LogStatus()
+func (amx *AMXConfig) Init() error {
+
+	amx.RunTime = time.Now()
+	amx.RunID = amx.RunTime.Format(constants.RunIDFormat)
+
+	registry, err := segments.Load(amx.AppConfig, constants.Segments)
+	if err != nil {
+		return validationError("init", "Invalid segment registry in "+constants.ApplicationConfig, err)
 	}
+	amx.registry = registry
 
-	res, _ := io.ReadAll(response.Body)
-	log.Info().Stringer("Requesting Url", req.URL).RawJSON("Request", json_req).Interface("Headers", req.Header).RawJSON("Response", res).Msg("")
+	fields := append(entities.ScripFields(), "segment")
+	engine, err := rules.Load(amx.RulesConfig, fields, segments.Equity, segments.Derivative)
+	if err != nil {
+		return validationError("init", "Invalid filter rules in "+constants.RulesConfig, err)
+	}
+	amx.dates = dates.New(registry.Epochs(), amx.AppConfig.GetInt(constants.ExpiryGraceDays))
+	engine.Dates = amx.dates
+	if amx.Now != nil {
+		engine.Now = amx.Now
+	}
+	amx.rules = engine
+	amx.MSSQLEntities = mssql.MSSQL{Server: amx.AppConfig.GetString(constants.Server), Database: amx.AppConfig.GetString(constants.Database), Port: amx.AppConfig.GetInt(constants.Port), User: amx.AppConfig.GetString(constants.User), Password: amx.AppConfig.GetString(constants.Password),
+		BatchSize: amx.AppConfig.GetInt(constants.BulkBatchSize), Parallelism: amx.AppConfig.GetInt(constants.BulkParallelism)}
+
+	env := amx.AppConfig.GetString(constants.Env)
+	amx.client = amxapi.NewClient(amxapi.Config{
+		LoginURL:    amx.UrlConfig.GetString(env + "." + constants.GetLoginUrl),
+		SecInfoURL:  amx.UrlConfig.GetString(env + "." + constants.GetSecinfoUrl),
+		UserID:      amx.AppConfig.GetString(env + "." + constants.UserID),
+		Password:    amx.AppConfig.GetString(env + "." + constants.UserPassword),
+		Timeout:     amx.AppConfig.GetDuration(constants.AMXTimeout),
+		MaxRetries:  amx.AppConfig.GetInt(constants.AMXMaxRetries),
+		BackoffBase: amx.AppConfig.GetDuration(constants.AMXBackoffBase),
+		BackoffMax:  amx.AppConfig.GetDuration(constants.AMXBackoffMax),
+	})
+
+	amx.mojo = mojo.NewClient(mojo.Config{
+		URL:         amx.UrlConfig.GetString(env + "." + constants.StockMasterUrl),
+		Timeout:     amx.AppConfig.GetDuration(constants.MojoTimeout),
+		MaxRetries:  amx.AppConfig.GetInt(constants.MojoMaxRetries),
+		BackoffBase: amx.AppConfig.GetDuration(constants.MojoBackoffBase),
+		BackoffMax:  amx.AppConfig.GetDuration(constants.MojoBackoffMax),
+	})
+
+	amx.MSSQLEntities.Open(mssql.PoolConfig{
+		MaxOpen:          amx.AppConfig.GetInt(constants.PoolMaxOpen),
+		MaxIdle:          amx.AppConfig.GetInt(constants.PoolMaxIdle),
+		ConnMaxLifetime:  amx.AppConfig.GetDuration(constants.PoolMaxLifetime),
+		HealthInterval:   amx.AppConfig.GetDuration(constants.PoolHealthCheck),
+		Retry:            amx.AppConfig.GetInt(constants.Retry),
+		BackoffBase:      amx.AppConfig.GetDuration(constants.PoolBackoffBase),
+		BackoffMax:       amx.AppConfig.GetDuration(constants.PoolBackoffMax),
+		BreakerThreshold: amx.AppConfig.GetInt(constants.BreakerThreshold),
+		BreakerCooldown:  amx.AppConfig.GetDuration(constants.BreakerCooldown),
+	})
+
+	if amx.DBConfig != nil {
+		if err := amx.MSSQLEntities.Configure(amx.DBConfig); err != nil {
+			return validationError("init", "Invalid procedure configuration in "+constants.DatabaseConfig, err)
+		}
+	}
 
-	var apiRes map[string]interface{}
-	json.Unmarshal(res, &apiRes)
+	if amx.Store == nil {
+		store, err := amx.openStore()
+		if err != nil {
+			return err
+		}
+		amx.Store = store
+	}
+	return nil
+}
 
-	if strings.EqualFold(apiRes[constants.Message].(string), constants.Success) {
+// Close releases the storage of the run.
+func (amx *AMXConfig) Close() error {
 
-		data := apiRes[constants.Data].(map[string]interface{})
-		return data["accesstoken"].(string)
+	if closer, ok := amx.Store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
 
-	} else {
+// openStore returns the storage selected by the storage setting.
+func (amx *AMXConfig) openStore() (persistance.Database, error) {
 
-		amx.Log.IsAPIFailed = true
-		amx.Log.FailureMessage = apiRes[constants.ErrCode].(string) + " " + apiRes[constants.Message].(string)
-		amx.Log.Details = "AMX login api has been failed"
-		amx.Log.Url = url
-		amx.LogStatus()
+	switch storage := amx.AppConfig.GetString(constants.Storage); storage {
+	case "", constants.StorageMSSQL:
+		if amx.DBConfig == nil {
+			return nil, validationError("init", "MSSQL storage needs "+constants.DatabaseConfig, nil)
+		}
+		return amx.MSSQLEntities, nil
+	case constants.StorageMemory:
+		store, err := memory.New(amx.AppConfig.GetString(constants.StoragePath))
+		if err != nil {
+			return nil, validationError("init", "Unable to open the memory storage", err)
+		}
+		return store, nil
+	default:
+		return nil, validationError("init", "Invalid storage in "+constants.ApplicationConfig, fmt.Errorf("unknown storage %q", storage))
 	}
+}
+
+func (amx *AMXConfig) Login() (string, error) {
 
-	return ""
+	accToken, err := amx.client.Login(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("AMX Login Failed")
+		return "", apiError("login", "AMX login api has been failed", err)
+	}
+	return accToken, nil
 }
 
-func (amx *AMXConfig) Build(accToken string) {
+// Build fetches the enabled segments from AMX and loads them. Cancelling
+// ctx stops the fetch and the load.
+func (amx *AMXConfig) Build(ctx context.Context, accToken string) error {
 
-	url := amx.UrlConfig.GetString(amx.AppConfig.GetString(constants.Env) + "." + constants.GetSecinfoUrl)
-	segmentData := make(map[string][]interface{})
+	amx.client.SetToken(accToken)
 
-	amx.Delete_Records(amx.DBConfig.GetString(constants.DeleteEquity), "Equity")
-	amx.Delete_Records(amx.DBConfig.GetString(constants.DeleteDerivative), "Derivative")
+	pages := amx.openArchive()
+	err := amx.process(ctx, amx.fetchPages(pages))
+	if pages != nil {
+		amx.closeArchive(pages)
+	}
+	return err
+}
 
-	wg.Add(len(amx.vSegments))
+// fetchPages returns the source of the pages of getAllSecInfo. Every page
+// is archived when pages is set.
+func (amx *AMXConfig) fetchPages(pages *archive.Writer) pageSource {
 
-	for _, segments := range amx.vSegments {
+	return func(ctx context.Context, seg segments.Segment, emit func([]json.RawMessage) error) error {
 
 		isLastPage := false
-		page := "1"
+		page := 1
 
 		for isLastPage == false {
 
-			finalUrl := url + "exchange=" + segments + "&page=" + page
-
-			client := http.Client{}
-			req, _ := http.NewRequest("GET", finalUrl, nil)
-			req.Header.Set("Authorization", "Bearer "+accToken)
-
-			response, httpErr := client.Do(req)
-			if httpErr != nil {
-				log.Error().Str("Segment", segments).Str("Page", page).Err(httpErr).Stringer("Requesting Url", req.URL).Interface("Headers", req.Header).Msg("AMX Scripmaster api failed")
-				amx.Log.IsAPIFailed = true
-				amx.Log.FailureMessage = httpErr.Error()
-				amx.Log.Details = "AMX ScripMaster api has been failed"
-				amx.Log.Url = url
-				amx.LogStatus()
+			secInfo, err := amx.client.SecInfo(ctx, seg.Code, page)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Error().Str("Segment", seg.Code).Int("Page", page).Err(err).Msg("AMX Scripmaster api failed")
+				}
+				return apiError("fetch", "AMX ScripMaster api has been failed", err)
 			}
 
-			res, _ := io.ReadAll(response.Body)
-			log.Info().Stringer("Requesting Url", req.URL).Interface("Headers", req.Header).RawJSON("Response", res).Msg("")
-
-			var apiRes map[string]interface{}
-			json.Unmarshal(res, &apiRes)
-			if strings.EqualFold(apiRes[constants.Message].(string), constants.Success) {
-
-				data := apiRes[constants.Data].(map[string]interface{})
-				isLastPage = data[constants.LastPage].(bool)
-				page = strconv.Itoa(int(math.Round(data[constants.NextPage].(float64))))
-				vData := data[constants.Data].([]interface{})
-
-				segmentData[segments] = append(segmentData[segments], vData)
-
-			} else {
-
-				amx.Log.IsAPIFailed = true
-				amx.Log.FailureMessage = apiRes[constants.Message].(string)
-				amx.Log.Details = "AMX ScripMaster api has been failed"
-				amx.Log.Url = url
-				amx.LogStatus()
-
+			if pages != nil {
+				if aErr := pages.Add(seg.Code, page, secInfo.Raw); aErr != nil {
+					log.Warn().Str("Segment", seg.Code).Int("Page", page).Err(aErr).Msg("Unable to archive page")
+				}
 			}
-		}
-		log.Info().Str("Segment", segments).Msg("API call completed for segment " + segments)
-
-		if segments == "nse_cm" || segments == "bse_cm" {
-
-			go amx.Parse_EQ(segmentData[segments], segments)
-			delete(segmentData, segments)
 
-		} else {
+			if err := emit(secInfo.Records); err != nil {
+				return apiError("fetch", "Build cancelled", err)
+			}
+			isLastPage = secInfo.HasLastPage
 
-			go amx.Parse_Derv(segmentData[segments], segments)
-			delete(segmentData, segments)
+			if !isLastPage {
+				next, nErr := secInfo.NextPage.Float64()
+				if nErr != nil || int(math.Round(next)) <= page {
+					return apiError("fetch", "AMX ScripMaster api has been failed", fmt.Errorf("%s: invalid nextPage %q after page %d", seg.Code, secInfo.NextPage, page))
+				}
+				page = int(math.Round(next))
+			}
 		}
+		log.Info().Str("Segment", seg.Code).Msg("API call completed for segment " + seg.Code)
+		return nil
 	}
-	wg.Wait()
 }
 
-func (amx *AMXConfig) Parse_EQ(segData []interface{}, segment string) {
+// process parses, checks and loads the pages of source as configured. In
+// swap mode the pages are staged as they are parsed. The direct load
+// deletes the live master first and the dry run writes every call at the
+// end, so both hold the parsed segments whole until the gates passed.
+func (amx *AMXConfig) process(ctx context.Context, source pageSource) error {
 
-	defer wg.Done()
-
-	var count, skip_count int
-	var db *sql.DB
-	var err error
-
-	db, err = amx.MSSQLEntities.GetDBConnection()
+	cfg, err := amx.gateConfig()
 	if err != nil {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = err.Error()
-		amx.Log.Details = "MSSQL - Failed to create connection"
-		amx.LogStatus()
+		return validationError("gates", "Invalid gates in "+constants.ApplicationConfig, err)
 	}
 
-	if !amx.MSSQLEntities.MssqlConnCheck(db) {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = "MSSQL Reconnect attepmts has been failed"
-		amx.Log.Details = "MSSQL - Connection Inactive"
-		amx.LogStatus()
+	if amx.AppConfig.GetString(constants.LoadMode) == constants.LoadModeSwap && !amx.DryRun {
+		return amx.loadSwap(ctx, source, cfg)
 	}
 
-	defer mssql.CloseDBConnection(db)
+	parsed, tallies, err := amx.parsePages(ctx, source, cfg.RequiredUnderlyings)
+	if err != nil {
+		return err
+	}
 
-	for outer_index := 0; outer_index < len(segData); outer_index++ {
-		for inner_index := 0; inner_index < len(segData[outer_index].([]interface{})); inner_index++ {
-			count++
-			data := segData[outer_index].([]interface{})[inner_index].(map[string]interface{})
+	Write_Summary(os.Stdout, tallies)
 
-			if data["remarksText"].(string) == "SP" ||
-				data["symbol"].(string) == "" {
+	if amx.AppConfig.GetBool(constants.ExportEnabled) {
+		if err := amx.Export_Parsed(parsed); err != nil {
+			log.Error().Err(err).Msg("Scrip master export failed")
+		}
+	}
 
-				skip_count++
-				log.Debug().Interface("Data", data).Str("Segment", segment).Msg("Skipped empty symbol / Invalid remarks")
-				continue //Skipping
-			}
+	previous, snapshot, diffEnabled := amx.previousMaster()
+	if err := amx.Check_Gates(cfg, tallies, previous, snapshot); err != nil {
+		return err
+	}
 
-			if segment == "nse_cm" && !amx.Check_Series(segment, data["series"].(string)) {
+	// the rejected records are kept once the master is loaded
+	if !amx.DryRun {
+		if err := amx.Load_Direct(ctx, parsed); err != nil {
+			return err
+		}
+	}
+	if err := amx.Quarantine_Rejected(parsed); err != nil {
+		log.Error().Err(err).Msg("Unable to quarantine rejected records")
+	}
+	if amx.DryRun {
+		return amx.Load_DryRun(parsed)
+	}
 
-				skip_count++
-				log.Debug().Interface("Data", data).Str("Segment", segment).Msg("Skipped invalid series")
-				continue //Skipping
-			}
+	if diffEnabled {
+		if dErr := amx.Write_Diff(previous, masterRecords(parsed)); dErr != nil {
+			log.Error().Err(dErr).Msg("Unable to write diff report")
+		}
+	}
+	return nil
+}
 
-			token := data["symbol"].(string)
-			if segment == "bse_cm" && !strings.HasPrefix(token, "7") &&
-				!strings.HasPrefix(token, "5") && (!strings.HasPrefix(token, "8") && !amx.Check_Series(segment, data["series"].(string))) {
+func (amx *AMXConfig) Parse_EQ(segData []json.RawMessage, seg segments.Segment) *ParsedSegment {
 
-				skip_count++
-				log.Debug().Interface("Data", data).Str("Segment", segment).Msg("Skipped invalid series / token")
-				continue //Skipping
-			}
+	segment := seg.Code
+	result := &ParsedSegment{Segment: segment}
 
-			divider, precision := helper.GetDividerAndPrecision(data["marketSegmentId"].(string))
-			assetClass := "cash"
-			expDate := "01 Jan 1980"
+	for _, record := range segData {
+		result.Count++
+		data, decErr := decodeRecord(record)
+		if decErr != nil {
 
-			var details = "-"
-			if data["securityDesc"].(string) != "" {
-				details = data["securityDesc"].(string)
-			}
+			result.skip(amx.RunID, ReasonDecodeError, record, decErr)
+			log.Warn().Bytes("Data", record).Str("Segment", segment).Err(decErr).Msg("Skipped undecodable record")
+			continue //Skipping
+		}
 
-			sQuery := amx.DBConfig.GetString(constants.EQInsertQuery)
-			tsql := fmt.Sprintf(
-				sQuery,
-				data["symbol"].(string)+"_"+helper.GetSegmentId(data["marketSegmentId"].(string)),
-				data["symbol"].(string),
-				data["symbolName"].(string),
-				data["series"].(string),
-				data["instrumentType"].(string),
-				divider, precision, assetClass,
-				helper.GetMaturityDate(data["issueMaturityDate"].(string)),
-				details,
-				helper.SetPrecision(strconv.Itoa(int(data["priceTick"].(float64))), divider, precision),
-				strconv.Itoa(int(data["minimumLot"].(float64))),
-				strconv.Itoa(int(data["lowPriceRange"].(float64))),
-				strconv.Itoa(int(data["highPriceRange"].(float64))),
-				data["assetToken"].(string),
-				data["instrumentType"].(string),
-				data["expiryDate"].(string),
-				expDate,
-				strconv.Itoa(int(data["strikePrice"].(float64))),
-				data["optionType"].(string),
-				helper.GetSegmentId(data["marketSegmentId"].(string)),
-				data["faceValue"].(string),
-				data["isinCode"].(string),
-				strconv.Itoa(int(data["priceQuotUnit"].(float64))),
-				strconv.Itoa(int(data["maxSingleTransQty"].(float64))),
-				strconv.Itoa(int(data["maxSingleTransValue"].(float64))),
-				data["qtyUnits"].(string),
-				"1",
-				"1",
-				data["marketType"].(string),
-				strconv.Itoa(int(data["openInterest"].(float64))),
-				strconv.Itoa(int(data["totalValueTraded"].(float64))),
-				details,
-				helper.GetFreezepercentage(strconv.Itoa(int(data["freezePercent"].(float64))), divider, precision),
-				data["deliveryUnit"].(string),
-				strconv.Itoa(int(data["basePrice"].(float64))),
-				strconv.Itoa(int(data["issueCapital"].(float64))),
-				strconv.Itoa(int(data["regularLot"].(float64))),
-				data["priceQuotFactor"].(string),
-				data["issueStartDate"].(string),
-				data["trdSymbol"].(string))
-
-			ctx := context.Background()
-			_, qErr := db.ExecContext(ctx, tsql)
-
-			if qErr != nil {
-				log.Error().Stack().Str("Query", tsql).Err(qErr).Msg("Error in updating AMX ScripMaster")
-				amx.Log.IsDBFailed = true
-				amx.Log.FailureMessage = qErr.Error()
-				amx.Log.Details = "Query execution failed"
-				amx.LogStatus()
-			}
+		decision, ruleErr := amx.rules.Evaluate(segments.Equity, ruleFields(seg, data))
+		if ruleErr != nil {
+
+			result.skip(amx.RunID, ReasonRuleError, record, ruleErr)
+			log.Error().Str("Segment", segment).Err(ruleErr).Msg("Skipped record the filter rules could not decide")
+			continue //Skipping
 		}
-	}
+		if !decision.Include {
 
-	log.Info().Str("Segment", segment).Int("Processed Count", count).Int("Skipped Count", skip_count).Msg(segment + " has been processed")
-}
+			result.skip(amx.RunID, decision.Reason, record, nil)
+			log.Debug().Interface("Data", data).Str("Segment", segment).Str("Reason", decision.Reason).Msg("Skipped by filter rules")
+			continue //Skipping
+		}
 
-func (amx *AMXConfig) Parse_Derv(segData []interface{}, segment string) {
+		divider, precision := seg.Divider, seg.Precision
 
-	defer wg.Done()
+		var details = "-"
+		if data.SecurityDesc != "" {
+			details = data.SecurityDesc
+		}
 
-	var count, skip_count int
-	var db *sql.DB
-	var err error
+		eq := entities.EquityScrip{
+			Scrip:      data,
+			TokenMktID: data.Symbol + "_" + seg.MarketID,
+			SegmentID:  seg.MarketID,
+			Divider:    divider,
+			Precision:  precision,
+			AssetClass: seg.AssetClass,
+			ExpDate:    "01 Jan 1980",
+			Maturity:   amx.dates.Format(seg.Code, data.IssueMaturityDate, constants.MatDateTimeFomat),
+			Details:    details,
+		}
 
-	db, err = amx.MSSQLEntities.GetDBConnection()
-	if err != nil {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = err.Error()
-		amx.Log.Details = "MSSQL - Failed to create connection"
-		amx.LogStatus()
+		result.Equity = append(result.Equity, eq)
 	}
 
-	if !amx.MSSQLEntities.MssqlConnCheck(db) {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = "MSSQL Reconnect attepmts has been failed"
-		amx.Log.Details = "MSSQL - Connection Inactive"
-		amx.LogStatus()
-	}
+	log.Debug().Str("Segment", segment).Int("Processed Count", result.Count).Int("Skipped Count", result.SkipCount).Msg(segment + " page has been parsed")
 
-	defer mssql.CloseDBConnection(db)
-
-	for outer_index := 0; outer_index < len(segData); outer_index++ {
-		for inner_index := 0; inner_index < len(segData[outer_index].([]interface{})); inner_index++ {
-			count++
-			data := segData[outer_index].([]interface{})[inner_index].(map[string]interface{})
-
-			var assetClass, details, strikePrice, optionType string
-
-			instName := data["instrumentType"].(string)
-			expDate := data["expiryDate"].(string)
-			divider, precision := helper.GetDividerAndPrecision(data["marketSegmentId"].(string))
-			strikePrice = strconv.Itoa(int(data["strikePrice"].(float64)))
-			optionType = data["optionType"].(string)
-			assetClass = "derivative"
-			var priceNum string
-			priceNum = "1"
-			if segment == "mcx_fo" || segment == "ncx_fo" {
-
-				if int(data["genDen"].(float64)) == 0 || int(data["priceDen"].(float64)) == 0 {
-					priceNum = "1"
-				} else {
-					priceNum = strconv.Itoa(int(data["genNum"].(float64)) / int(data["genDen"].(float64)) * int(data["priceNum"].(float64)) / int(data["priceDen"].(float64)))
-				}
-			}
+	return result
+}
 
-			if strings.HasPrefix(instName, "FUT") || strings.HasPrefix(instName, "OPT") {
-				if expDate == "" {
+func (amx *AMXConfig) Parse_Derv(segData []json.RawMessage, seg segments.Segment) *ParsedSegment {
 
-					skip_count++
-					log.Debug().Interface("Data", data).Str("Segment", segment).Msg("Skipped Empty Expiry")
-					continue //Skipping
-				}
+	segment := seg.Code
+	result := &ParsedSegment{Segment: segment}
 
-				if Expiry_Validate(expDate) {
+	for _, record := range segData {
+		result.Count++
+		data, decErr := decodeRecord(record)
+		if decErr != nil {
 
-					skip_count++
-					log.Debug().Interface("Data", data).Str("Segment", segment).Msg("Skipped Expired Contract")
-					continue //Skipping
-				}
+			result.skip(amx.RunID, ReasonDecodeError, record, decErr)
+			log.Warn().Bytes("Data", record).Str("Segment", segment).Err(decErr).Msg("Skipped undecodable record")
+			continue //Skipping
+		}
 
-				expDate = helper.GetTimeInFormat(expDate, constants.ExpFormat)
+		var details string
 
-				details += expDate
-				if strings.HasPrefix(instName, "OPT") {
-					details += " " + optionType + " " + helper.FormatStrikePrice(strikePrice, divider, precision)
-				}
+		instName := data.InstrumentType
+		expDate := data.ExpiryDate
+		divider, precision := seg.Divider, seg.Precision
+		optionType := data.OptionType
 
-			} else if amx.Check_Index(instName) {
+		decision, ruleErr := amx.rules.Evaluate(segments.Derivative, ruleFields(seg, data))
+		if ruleErr != nil {
 
-				details += data["securityDesc"].(string)
+			result.skip(amx.RunID, ReasonRuleError, record, ruleErr)
+			log.Error().Str("Segment", segment).Err(ruleErr).Msg("Skipped record the filter rules could not decide")
+			continue //Skipping
+		}
+		if !decision.Include {
+
+			result.skip(amx.RunID, decision.Reason, record, nil)
+			log.Debug().Interface("Data", data).Str("Segment", segment).Str("Reason", decision.Reason).Msg("Skipped by filter rules")
+			continue //Skipping
+		}
 
-			} else {
+		spec := commodity.Spec{Multiplier: commodity.One}
+		if seg.Parser == segments.Commodity {
 
-				skip_count++
-				log.Debug().Interface("Data", data).Str("Segment", segment).Msg("Skipped Invalid Derivative Contract")
+			var specErr error
+			if spec, specErr = commodity.New(data); specErr != nil {
+
+				result.skip(amx.RunID, ReasonInvalidContractSpec, record, specErr)
+				log.Warn().Interface("Data", data).Str("Segment", segment).Err(specErr).Msg("Skipped contract with an invalid specification")
 				continue //Skipping
 			}
-
-			sQuery := amx.DBConfig.GetString(constants.DERInsertQuery)
-			tsql := fmt.Sprintf(
-				sQuery,
-				data["symbol"].(string)+"_"+helper.GetSegmentId(data["marketSegmentId"].(string)),
-				data["symbol"].(string),
-				data["symbolName"].(string),
-				data["series"].(string),
-				instName,
-				strconv.Itoa(int(data["normalMarketAllowed"].(float64))),
-				divider, precision, assetClass,
-				helper.GetMaturityDate(data["issueMaturityDate"].(string)),
-				data["securityDesc"].(string),
-				helper.SetPrecision(strconv.Itoa(int(data["priceTick"].(float64))), divider, precision),
-				strconv.Itoa(int(data["minimumLot"].(float64))),
-				strconv.Itoa(int(data["lowPriceRange"].(float64))),
-				strconv.Itoa(int(data["highPriceRange"].(float64))),
-				data["assetToken"].(string),
-				instName,
-				data["expiryDate"].(string),
-				expDate, strikePrice, optionType,
-				helper.GetSegmentId(data["marketSegmentId"].(string)),
-				data["faceValue"].(string),
-				data["isinCode"].(string),
-				strconv.Itoa(int(data["priceQuotUnit"].(float64))),
-				strconv.Itoa(int(data["maxSingleTransQty"].(float64))),
-				strconv.Itoa(int(data["maxSingleTransValue"].(float64))),
-				data["qtyUnits"].(string),
-				priceNum,
-				"1",
-				data["marketType"].(string),
-				strconv.Itoa(int(data["openInterest"].(float64))),
-				strconv.Itoa(int(data["totalValueTraded"].(float64))),
-				details,
-				helper.GetFreezepercentage(strconv.Itoa(int(data["freezePercent"].(float64))), divider, precision),
-				data["deliveryUnit"].(string),
-				strconv.Itoa(int(data["basePrice"].(float64))),
-				strconv.Itoa(int(data["issueCapital"].(float64))),
-				strconv.Itoa(int(data["regularLot"].(float64))),
-				data["priceQuotFactor"].(string),
-				data["issueStartDate"].(string),
-				data["trdSymbol"].(string))
-
-			ctx := context.Background()
-			_, qErr := db.ExecContext(ctx, tsql)
-
-			if qErr != nil {
-				log.Error().Stack().Str("Query", tsql).Err(qErr).Msg("Error in updating AMX ScripMaster")
-				amx.Log.IsDBFailed = true
-				amx.Log.FailureMessage = qErr.Error()
-				amx.Log.Details = "Query execution failed"
-				amx.LogStatus()
+			if spec.Invalid != "" {
+				log.Warn().Str("Segment", segment).Str("Token", data.Symbol).Str("Invalid", spec.Invalid).Msg("Invalid contract specification, price multiplier taken as 1")
 			}
 		}
-	}
-
-	log.Info().Str("Segment", segment).Int("Processed Count", count).Int("Skipped Count", skip_count).Msg(segment + " has been processed")
-}
 
-func (amx *AMXConfig) BackUp_AMXScripMaster() {
+		if strings.HasPrefix(instName, "FUT") || strings.HasPrefix(instName, "OPT") {
 
-	log.Info().Msg("Backing Up Data")
-	var db *sql.DB
-	var err error
+			expDate = amx.dates.Format(seg.Code, expDate, constants.ExpFormat)
 
-	db, err = amx.MSSQLEntities.GetDBConnection()
-	if err != nil {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = err.Error()
-		amx.Log.Details = "MSSQL - Failed to create connection"
-		amx.LogStatus()
-	}
+			details += expDate
+			if strings.HasPrefix(instName, "OPT") {
+				details += " " + optionType + " " + helper.FormatStrikePrice(data.StrikePrice, divider, precision)
+			}
 
-	if !amx.MSSQLEntities.MssqlConnCheck(db) {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = "MSSQL Reconnect attepmts has been failed"
-		amx.Log.Details = "MSSQL - Connection Inactive"
-		amx.LogStatus()
-	}
+		} else {
 
-	log.Info().Str("Server", amx.MSSQLEntities.Server).Msg("Connected")
+			details += data.SecurityDesc
+		}
 
-	defer mssql.CloseDBConnection(db)
+		derv := entities.DerivativeScrip{
+			Scrip:      data,
+			TokenMktID: data.Symbol + "_" + seg.MarketID,
+			SegmentID:  seg.MarketID,
+			Divider:    divider,
+			Precision:  precision,
+			AssetClass: seg.AssetClass,
+			ExpDate:    expDate,
+			Maturity:   amx.dates.Format(seg.Code, data.IssueMaturityDate, constants.MatDateTimeFomat),
+			Details:    details,
+			PriceNumer: strconv.FormatInt(spec.Multiplier.Num(), 10),
+			PriceDenom: strconv.FormatInt(spec.Multiplier.Den(), 10),
+		}
 
-	sQuery := amx.DBConfig.GetString(constants.BackUpProcedure)
-	ctx := context.Background()
-	_, qErr := db.ExecContext(ctx, sQuery)
-
-	if qErr != nil {
-		log.Error().Str("Query", sQuery).Err(qErr).Msg("Error in backup AMXScripmaster")
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = qErr.Error()
-		amx.Log.Details = "Query execution failed"
-		amx.LogStatus()
+		result.Derivatives = append(result.Derivatives, derv)
 	}
 
-	log.Info().Msg("Back Up Completed...")
+	log.Debug().Str("Segment", segment).Int("Processed Count", result.Count).Int("Skipped Count", result.SkipCount).Msg(segment + " page has been parsed")
 
-	amx.ISBackupDone = true
+	return result
 }
 
-func (amx *AMXConfig) Delete_Records(sQuery, segment string) {
-
-	if !amx.ISBackupDone {
-		return
-	}
-
-	log.Info().Str("Segment", segment).Msg("Started deleting the records for " + segment)
-
-	var db *sql.DB
-	var err error
-
-	db, err = amx.MSSQLEntities.GetDBConnection()
-	if err != nil {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = err.Error()
-		amx.Log.Details = "MSSQL - Failed to create connection"
-		amx.LogStatus()
-	}
+func (amx *AMXConfig) BackUp_AMXScripMaster() error {
 
-	if !amx.MSSQLEntities.MssqlConnCheck(db) {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = "MSSQL Reconnect attepmts has been failed"
-		amx.Log.Details = "MSSQL - Connection Inactive"
-		amx.LogStatus()
-	}
-
-	defer mssql.CloseDBConnection(db)
+	log.Info().Msg("Backing Up Data")
 
 	ctx := context.Background()
-	_, qErr := db.ExecContext(ctx, sQuery)
-
-	if qErr != nil {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = qErr.Error()
-		amx.Log.Details = "Query execution failed"
-		amx.LogStatus()
+	if err := amx.Store.BackUp(ctx, amx.RunID, amx.RunTime); err != nil {
+		log.Error().Str("Run ID", amx.RunID).Err(err).Msg("Error in backup AMXScripmaster")
+		return dbError("backup", "Query execution failed", err)
 	}
 
-	log.Info().Str("Segment", segment).Msg(segment + " records cleaned...")
+	log.Info().Str("Run ID", amx.RunID).Msg("Back Up Completed...")
+
+	amx.ISBackupDone = true
+	return nil
 }
 
-func (amx *AMXConfig) Check_Series(segment string, series string) bool {
+func (amx *AMXConfig) Delete_Records(ctx context.Context, assetClass string) error {
 
-	switch {
-	case segment == "nse_cm":
-		for _, s := range amx.vNse_Series {
-			if s == series {
-				return true
-			}
-		}
-		return false
-	case segment == "bse_cm":
-		for _, s := range amx.vBse_Series {
-			if s == series {
-				return true
-			}
-		}
-		return false
-	default:
-		return false
+	if !amx.ISBackupDone {
+		return nil
 	}
-}
 
-func (amx *AMXConfig) Check_Index(instName string) bool {
+	log.Info().Str("Segment", assetClass).Msg("Started deleting the records for " + assetClass)
+	amx.markAffected(assetClass)
 
-	for _, s := range amx.vIndex_Instruments {
-		if s == instName {
-			return true
-		}
+	if err := amx.Store.Delete(ctx, assetClass); err != nil {
+		return dbError("delete", assetClass+" - Query execution failed", err)
 	}
 
-	return false
+	log.Info().Str("Segment", assetClass).Msg(assetClass + " records cleaned...")
+	return nil
 }
 
-func Expiry_Validate(expDate string) bool {
-
-	if expDate != "" {
+// segmentNames maps the market segment ids of the registry back to their
+// exchange code.
+func (amx *AMXConfig) segmentNames() map[string]string {
+	return amx.registry.Names()
+}
 
-		iTime, _ := strconv.ParseInt(expDate, 10, 64)
-		now := time.Now()
+// ruleFields exposes the scrip fields and the exchange code of its segment
+// to the filter rules.
+func ruleFields(seg segments.Segment, data entities.Scrip) rules.Fields {
 
-		if iTime == now.Unix() {
-			return false
-		}
-
-		if iTime < now.Unix() {
-			return true
+	return func(name string) (string, bool) {
+		if name == "segment" {
+			return seg.Code, true
 		}
+		return data.Field(name)
 	}
-	return false
 }
 
-func (amx *AMXConfig) LogStatus() {
-
-	if amx.Log.IsAPIFailed == true {
-
-		log.Error().Stack().Str("Details", amx.Log.Details).Str("Contact", "API Team").Str("Url", amx.Log.Url).Msg(amx.Log.FailureMessage)
-		os.Exit(1)
-
-	} else if amx.Log.IsDBFailed == true {
-
-		log.Error().Stack().Str("Details", amx.Log.Details).Str("Contact", "MSIL Team").Msg(amx.Log.FailureMessage)
-		os.Exit(1)
-
-	} else {
-
-		log.Info().Msg("Records updated into amx scripmaster successfully")
+func decodeRecord(record json.RawMessage) (entities.Scrip, error) {
 
+	raw, err := amxapi.DecodeRecord(record)
+	if err != nil {
+		return entities.Scrip{}, err
 	}
+	return entities.DecodeScrip(raw)
 }
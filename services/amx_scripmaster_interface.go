@@ -1,13 +1,45 @@
 package services
 
+import (
+	"context"
+	"encoding/json"
+
+	"main.go/diff"
+	"main.go/export"
+	"main.go/gates"
+	"main.go/persistance"
+	"main.go/segments"
+)
+
 type AMXScripmaster interface {
-	Init()
-	Login() string
-	Build(accToken string)
-	BackUp_AMXScripMaster()
-	Delete_Records(sQuery, segment string)
-	Build_MarketCap()
-	UpdateStockID()
-	Parse_EQ(segData []interface{}, segment string)
-	Parse_Derv(segData []interface{}, segment string)
+	Init() error
+	Close() error
+	Login() (string, error)
+	Build(ctx context.Context, accToken string) error
+	Run_Build(ctx context.Context) error
+	Run_Rebuild(ctx context.Context, runID string) error
+	Rebuild(ctx context.Context, runID string) error
+	BackUp_AMXScripMaster() error
+	Delete_Records(ctx context.Context, assetClass string) error
+	Restore_AMXScripMaster(assetClass, generation string) error
+	List_BackUps() ([]persistance.BackUp, error)
+	Inspect_BackUp(runID string) (map[string]int, error)
+	Prune_BackUps() ([]string, error)
+	Build_MarketCap() error
+	UpdateStockID() error
+	Update_StockIDs(ctx context.Context) (StockIDReport, error)
+	Parse_EQ(segData []json.RawMessage, seg segments.Segment) *ParsedSegment
+	Parse_Derv(segData []json.RawMessage, seg segments.Segment) *ParsedSegment
+	Quarantine_Rejected(parsed []*ParsedSegment) error
+	Check_Gates(cfg gates.Config, tallies []*Tally, previous []diff.Record, snapshot bool) error
+	Load_Direct(ctx context.Context, parsed []*ParsedSegment) error
+	Load_Bulk(ctx context.Context, parsed []*ParsedSegment, target persistance.Target) error
+	Load_DryRun(parsed []*ParsedSegment) error
+	Export_Parsed(parsed []*ParsedSegment) error
+	Export_Master() error
+	Read_Master() ([]export.Row, error)
+	Serve(ctx context.Context) error
+	Mark_Build() error
+	Snapshot_Master() ([]diff.Record, error)
+	Write_Diff(previous, current []diff.Record) error
 }
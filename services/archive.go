@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	amxapi "main.go/amx"
+	"main.go/archive"
+	"main.go/constants"
+	"main.go/segments"
+)
+
+// openArchive starts the page archive of this run under
+// archive.path/<run id>. A failure only disables the archive.
+func (amx *AMXConfig) openArchive() *archive.Writer {
+
+	if !amx.AppConfig.GetBool(constants.ArchiveEnabled) {
+		return nil
+	}
+
+	dir := filepath.Join(amx.AppConfig.GetString(constants.ArchivePath), amx.RunID)
+	w, err := archive.NewWriter(dir, amx.RunID)
+	if err != nil {
+		log.Warn().Str("Path", dir).Err(err).Msg("Unable to create page archive, pages are not archived")
+		return nil
+	}
+	return w
+}
+
+func (amx *AMXConfig) closeArchive(w *archive.Writer) {
+
+	if err := w.Close(); err != nil {
+		log.Warn().Err(err).Msg("Unable to write page archive manifest")
+		return
+	}
+	log.Info().Str("Path", filepath.Join(amx.AppConfig.GetString(constants.ArchivePath), amx.RunID)).Msg("Pages archived")
+}
+
+// Rebuild parses and loads the pages archived by an earlier run instead of
+// fetching them from AMX.
+func (amx *AMXConfig) Rebuild(ctx context.Context, runID string) error {
+
+	if runID == "" {
+		return validationError("rebuild", "rebuild needs --from-archive <run id>", nil)
+	}
+
+	dir := filepath.Join(amx.AppConfig.GetString(constants.ArchivePath), runID)
+	reader, err := archive.Open(dir)
+	if err != nil {
+		return validationError("rebuild", "No page archive in "+dir, err)
+	}
+
+	return amx.process(ctx, archivedPages(reader, runID))
+}
+
+// archivedPages returns the source of the pages of an archived run. The
+// pages of a segment have to be archived from the first to the last, a gap
+// fails the segment before any of its pages is read.
+func archivedPages(reader *archive.Reader, runID string) pageSource {
+
+	return func(ctx context.Context, seg segments.Segment, emit func([]json.RawMessage) error) error {
+
+		incomplete := func(page int) error {
+			return validationError("rebuild", "Page archive of run "+runID+" is incomplete", fmt.Errorf("%s: page %d not archived", seg.Code, page))
+		}
+
+		pages := reader.Pages(seg.Code)
+		for index, p := range pages {
+			if p.Page != index+1 {
+				return incomplete(index + 1)
+			}
+		}
+
+		complete := false
+		for _, p := range pages {
+			body, rErr := reader.Read(p)
+			if rErr != nil {
+				return validationError("rebuild", "Corrupt page archive of run "+runID, rErr)
+			}
+			res, dErr := amxapi.DecodeSecInfo(body)
+			if dErr != nil {
+				return validationError("rebuild", "Corrupt page archive of run "+runID, fmt.Errorf("%s: %w", p.File, dErr))
+			}
+			if eErr := emit(res.Data.Records); eErr != nil {
+				return validationError("rebuild", "Rebuild cancelled", eErr)
+			}
+			complete = res.Data.HasLastPage
+		}
+		if !complete {
+			return incomplete(len(pages) + 1)
+		}
+		log.Info().Str("Segment", seg.Code).Int("Pages", len(pages)).Str("Archive Run ID", runID).Msg("Archived pages read for segment " + seg.Code)
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"main.go/constants"
+	"main.go/persistance"
+)
+
+// List_BackUps returns the backup generations, newest first.
+func (amx *AMXConfig) List_BackUps() ([]persistance.BackUp, error) {
+
+	generations, err := amx.Store.ListBackUps(context.Background())
+	if err != nil {
+		return nil, dbError("backups", "Unable to list backups", err)
+	}
+	return generations, nil
+}
+
+// Inspect_BackUp returns the row count per market segment of a backup.
+func (amx *AMXConfig) Inspect_BackUp(runID string) (map[string]int, error) {
+
+	if runID == "" {
+		return nil, validationError("backups", "backups inspect needs a run id", nil)
+	}
+
+	counts, err := amx.Store.InspectBackUp(context.Background(), runID)
+	if err != nil {
+		return nil, dbError("backups", "Unable to inspect backup", err)
+	}
+	if len(counts) == 0 {
+		return nil, validationError("backups", "Backup generation not found", fmt.Errorf("no rows for run %s", runID))
+	}
+	return counts, nil
+}
+
+// Prune_BackUps drops the backup generations outside the retention policy.
+func (amx *AMXConfig) Prune_BackUps() ([]string, error) {
+
+	pruned, err := amx.pruneBackUps(context.Background())
+	if err != nil {
+		return pruned, dbError("backups", "Unable to prune backups", err)
+	}
+	return pruned, nil
+}
+
+// pruneBackUps applies backup_retention_generations and
+// backup_retention_days. The newest generation is always kept.
+func (amx *AMXConfig) pruneBackUps(ctx context.Context) ([]string, error) {
+
+	generations, err := amx.Store.ListBackUps(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := amx.AppConfig.GetInt(constants.RetentionGenerations)
+	days := amx.AppConfig.GetInt(constants.RetentionDays)
+	expired := RetentionExpired(generations, keep, days, time.Now())
+
+	var pruned []string
+	for _, g := range expired {
+		if pErr := amx.Store.PruneBackUp(ctx, g.RunID); pErr != nil {
+			log.Error().Str("Run ID", g.RunID).Err(pErr).Msg("Error in pruning backup")
+			return pruned, pErr
+		}
+		log.Info().Str("Run ID", g.RunID).Time("Taken At", g.TakenAt).Msg("Backup pruned")
+		pruned = append(pruned, g.RunID)
+	}
+	return pruned, nil
+}
+
+// RetentionExpired returns the generations (ordered newest first) that fall
+// outside the newest keep generations or are older than days. A zero limit
+// disables that rule.
+func RetentionExpired(generations []persistance.BackUp, keep, days int, now time.Time) []persistance.BackUp {
+
+	var expired []persistance.BackUp
+	for index, g := range generations {
+		if index == 0 {
+			continue
+		}
+		if (keep > 0 && index >= keep) || (days > 0 && now.Sub(g.TakenAt) > time.Duration(days)*24*time.Hour) {
+			expired = append(expired, g)
+		}
+	}
+	return expired
+}
+
+// latestBackUp resolves the generation used for a restore when none is given.
+func (amx *AMXConfig) latestBackUp(ctx context.Context) (string, error) {
+
+	generations, err := amx.Store.ListBackUps(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(generations) == 0 {
+		return "", fmt.Errorf("no backup generation available")
+	}
+	return generations[0].RunID, nil
+}
@@ -0,0 +1,819 @@
+package services_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"main.go/amx/amxtest"
+	"main.go/archive"
+	"main.go/constants"
+	"main.go/entities"
+	"main.go/persistance"
+	"main.go/persistance/memory"
+	"main.go/persistance/quarantine"
+	"main.go/services"
+)
+
+const fixtures = "../amx/amxtest/testdata"
+
+// fixtureNow is the clock of the builds. The fixture expiries are fixed
+// epoch seconds, 2000000000 live and 100000000 expired relative to it, so
+// the builds do not depend on the day the tests run.
+func fixtureNow() time.Time {
+	return time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+}
+
+// live rows of the fixtures after the filter rules
+var expected = map[string]int{"nse_cm": 3, "bse_cm": 2, "nse_fo": 5, "mcx_fo": 3}
+
+func readConfig(t *testing.T, name string) *viper.Viper {
+
+	t.Helper()
+	v := viper.New()
+	v.SetConfigFile(filepath.Join("..", constants.BaseConfigPathDefaultValue, name))
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return v
+}
+
+// newBuild returns a build against the fake server on an empty memory
+// store, writing every output file below a temporary directory.
+func newBuild(t *testing.T, server *amxtest.Server) (*services.AMXConfig, *memory.Store) {
+
+	t.Helper()
+	dir := t.TempDir()
+
+	app := readConfig(t, constants.ApplicationConfig)
+	env := app.GetString(constants.Env)
+	app.Set(env+"."+constants.UserID, amxtest.UserID)
+	app.Set(env+"."+constants.UserPassword, amxtest.Password)
+	app.Set(constants.AMXTimeout, "2s")
+	app.Set(constants.AMXMaxRetries, 2)
+	app.Set(constants.AMXBackoffBase, "1ms")
+	app.Set(constants.AMXBackoffMax, "5ms")
+	app.Set(constants.QuarantinePath, filepath.Join(dir, "quarantine"))
+	app.Set(constants.ReportPath, filepath.Join(dir, "reports"))
+	app.Set(constants.DryRunPath, filepath.Join(dir, "dryrun"))
+	app.Set(constants.BuildMarker, filepath.Join(dir, "status", "last_build.json"))
+	app.Set(constants.ExportEnabled, false)
+	app.Set(constants.ArchivePath, filepath.Join(dir, "archives"))
+	// the fixtures are far below the production row minimums
+	app.Set(constants.GatesMinRows, map[string]int{})
+
+	urls := readConfig(t, constants.APIConfig)
+	urls.Set(env+"."+constants.GetLoginUrl, server.LoginURL())
+	urls.Set(env+"."+constants.GetSecinfoUrl, server.SecInfoURL())
+
+	store, err := memory.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amx := &services.AMXConfig{
+		AppConfig:   app,
+		UrlConfig:   urls,
+		DBConfig:    readConfig(t, constants.DatabaseConfig),
+		RulesConfig: readConfig(t, constants.RulesConfig),
+		Store:       store,
+		Now:         fixtureNow,
+	}
+	if err := amx.Init(); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	return amx, store
+}
+
+func newServer(t *testing.T, pageSize int) *amxtest.Server {
+
+	t.Helper()
+	server := amxtest.NewServer()
+	t.Cleanup(server.Close)
+	if err := server.LoadFixtures(fixtures, pageSize); err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func build(amx *services.AMXConfig) error {
+
+	token, err := amx.Login()
+	if err != nil {
+		return err
+	}
+	return amx.Build(context.Background(), token)
+}
+
+func liveCounts(t *testing.T, amx *services.AMXConfig) map[string]int {
+
+	t.Helper()
+	rows, err := amx.Read_Master()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row["segment"]]++
+	}
+	return counts
+}
+
+func assertCounts(t *testing.T, got, want map[string]int) {
+
+	t.Helper()
+	for segment, n := range want {
+		if got[segment] != n {
+			t.Errorf("%s: %d rows, want %d (all %v)", segment, got[segment], n, got)
+		}
+	}
+	for segment, n := range got {
+		if _, ok := want[segment]; !ok {
+			t.Errorf("%s: unexpected %d rows", segment, n)
+		}
+	}
+}
+
+func TestBuildLoadsFixtures(t *testing.T) {
+
+	for _, mode := range []string{constants.LoadModeDirect, constants.LoadModeSwap} {
+		t.Run(mode, func(t *testing.T) {
+
+			server := newServer(t, 2)
+			amx, _ := newBuild(t, server)
+			amx.AppConfig.Set(constants.LoadMode, mode)
+			amx.ISBackupDone = true
+
+			if err := build(amx); err != nil {
+				t.Fatalf("build: %v", err)
+			}
+			assertCounts(t, liveCounts(t, amx), expected)
+
+			// 8 nse_fo records in pages of 2
+			if n := server.Requests("nse_fo"); n != 4 {
+				t.Errorf("nse_fo: %d page requests, want 4", n)
+			}
+		})
+	}
+}
+
+func TestBuildRetriesServerErrors(t *testing.T) {
+
+	server := newServer(t, 3)
+	server.Fail("nse_cm", 2, http.StatusServiceUnavailable, 2)
+	amx, _ := newBuild(t, server)
+	amx.ISBackupDone = true
+
+	if err := build(amx); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	assertCounts(t, liveCounts(t, amx), expected)
+	if n := server.Requests("nse_cm"); n != 4 {
+		t.Errorf("nse_cm: %d page requests, want 4", n)
+	}
+}
+
+func TestBuildFailsWithoutTouchingMaster(t *testing.T) {
+
+	cases := []struct {
+		name   string
+		inject func(*amxtest.Server)
+	}{
+		{"server error", func(s *amxtest.Server) { s.Fail("nse_fo", 2, http.StatusInternalServerError, 0) }},
+		{"malformed page", func(s *amxtest.Server) { s.Inject("nse_fo", 1, amxtest.Fault{Body: `{"status": true, "data": `}) }},
+		{"slow page", func(s *amxtest.Server) { s.Slow("nse_fo", 1, 500*time.Millisecond) }},
+	}
+
+	for _, mode := range []string{constants.LoadModeDirect, constants.LoadModeSwap} {
+		for _, c := range cases {
+			t.Run(mode+"/"+c.name, func(t *testing.T) {
+
+				server := newServer(t, 2)
+				c.inject(server)
+				amx, store := newBuild(t, server)
+				amx.AppConfig.Set(constants.LoadMode, mode)
+				amx.AppConfig.Set(constants.AMXTimeout, "100ms")
+				amx.AppConfig.Set(constants.AMXMaxRetries, 1)
+				if err := amx.Init(); err != nil {
+					t.Fatal(err)
+				}
+				amx.ISBackupDone = true
+
+				err := build(amx)
+				var stepErr *services.StepError
+				if !errors.As(err, &stepErr) || stepErr.Class != services.APIFailure {
+					t.Fatalf("got %v, want an API failure", err)
+				}
+				if code := services.ExitCode(err); code != services.ExitAPI {
+					t.Errorf("exit code %d, want %d", code, services.ExitAPI)
+				}
+				if store.Len() != 0 {
+					t.Errorf("%d rows loaded after a failed fetch", store.Len())
+				}
+			})
+		}
+	}
+}
+
+func TestBuildQuarantinesMalformedRecords(t *testing.T) {
+
+	for _, mode := range []string{constants.LoadModeDirect, constants.LoadModeSwap} {
+		t.Run(mode, func(t *testing.T) {
+
+			server := newServer(t, 0)
+			server.Malformed("nse_cm", 1, `"not a record"`, `{"symbol": {"nested": true}, "series": "EQ"}`)
+			amx, _ := newBuild(t, server)
+			amx.AppConfig.Set(constants.LoadMode, mode)
+			amx.AppConfig.Set(constants.GatesMaxSkip, 0)
+			amx.ISBackupDone = true
+
+			if err := build(amx); err != nil {
+				t.Fatalf("build: %v", err)
+			}
+			assertCounts(t, liveCounts(t, amx), expected)
+
+			path := filepath.Join(amx.AppConfig.GetString(constants.QuarantinePath), amx.RunID, "rejected.jsonl")
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			reasons := make(map[string]int)
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				var record struct {
+					Segment string `json:"segment"`
+					Reason  string `json:"reason"`
+				}
+				if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+					t.Fatal(err)
+				}
+				reasons[record.Segment+"/"+record.Reason]++
+			}
+			if n := reasons["nse_cm/"+services.ReasonDecodeError]; n != 2 {
+				t.Errorf("%d nse_cm decode errors quarantined, want 2 (%v)", n, reasons)
+			}
+			if n := reasons["nse_fo/expired"]; n != 1 {
+				t.Errorf("%d expired nse_fo contracts quarantined, want 1 (%v)", n, reasons)
+			}
+		})
+	}
+}
+
+func TestBuildLogsInAgainAfterTokenExpiry(t *testing.T) {
+
+	server := newServer(t, 2)
+	amx, _ := newBuild(t, server)
+	amx.ISBackupDone = true
+
+	token, err := amx.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.ExpireToken()
+	if err := amx.Build(context.Background(), token); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	assertCounts(t, liveCounts(t, amx), expected)
+	if n := server.Logins(); n != 2 {
+		t.Errorf("%d logins, want 2", n)
+	}
+}
+
+func TestLoginRejectsInvalidCredentials(t *testing.T) {
+
+	server := newServer(t, 2)
+	amx, _ := newBuild(t, server)
+	amx.AppConfig.Set(amx.AppConfig.GetString(constants.Env)+"."+constants.UserPassword, "wrong")
+	if err := amx.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := amx.Login()
+	if services.ExitCode(err) != services.ExitAPI {
+		t.Fatalf("got %v, want an API failure", err)
+	}
+}
+
+func TestRebuildReplacesPreviousMaster(t *testing.T) {
+
+	server := newServer(t, 2)
+	amx, store := newBuild(t, server)
+	if err := amx.BackUp_AMXScripMaster(); err != nil {
+		t.Fatal(err)
+	}
+	if err := build(amx); err != nil {
+		t.Fatalf("first build: %v", err)
+	}
+
+	// the second run sees one nse_cm scrip less
+	server.SetRecords("nse_cm", 2, readFixture(t, "nse_cm")[1:])
+
+	second, _ := newBuild(t, server)
+	second.Store = store
+	second.AppConfig.Set(constants.GatesMaxDrop, 50)
+	if err := second.BackUp_AMXScripMaster(); err != nil {
+		t.Fatal(err)
+	}
+	if err := build(second); err != nil {
+		t.Fatalf("second build: %v", err)
+	}
+
+	want := map[string]int{"nse_cm": 2, "bse_cm": 2, "nse_fo": 5, "mcx_fo": 3}
+	assertCounts(t, liveCounts(t, second), want)
+}
+
+func TestRebuildFromArchive(t *testing.T) {
+
+	server := newServer(t, 3)
+	amx, _ := newBuild(t, server)
+	amx.AppConfig.Set(constants.ArchiveEnabled, true)
+	amx.ISBackupDone = true
+	if err := build(amx); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	archived := filepath.Join(amx.AppConfig.GetString(constants.ArchivePath), amx.RunID)
+
+	// AMX is down for the rebuild
+	server.Close()
+
+	offline, _ := newBuild(t, server)
+	offline.AppConfig.Set(constants.ArchivePath, filepath.Dir(archived))
+	offline.ISBackupDone = true
+	if err := offline.Rebuild(context.Background(), amx.RunID); err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	assertCounts(t, liveCounts(t, offline), expected)
+
+	// a gap in the pages of a segment names the missing page
+	manifest := filepath.Join(archived, archive.ManifestFile)
+	complete, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, missing := range []int{2, 3} {
+		dropPage(t, archived, "nse_fo", missing)
+		err := offline.Rebuild(context.Background(), amx.RunID)
+		if services.ExitCode(err) != services.ExitValidation || !strings.Contains(err.Error(), fmt.Sprintf("nse_fo: page %d not archived", missing)) {
+			t.Errorf("page %d missing: got %v, want a validation failure naming the page", missing, err)
+		}
+		if err := os.WriteFile(manifest, complete, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertCounts(t, liveCounts(t, offline), expected)
+
+	// a page that does not match its manifest hash is rejected
+	page := filepath.Join(archived, "nse_fo", "page-00002.json.gz")
+	if err := os.WriteFile(page, []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = offline.Rebuild(context.Background(), amx.RunID)
+	if services.ExitCode(err) != services.ExitValidation {
+		t.Fatalf("got %v, want a validation failure", err)
+	}
+}
+
+// dropPage removes a page of segment from the manifest of an archived run.
+func dropPage(t *testing.T, dir, segment string, page int) {
+
+	t.Helper()
+	reader, err := archive.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pages []archive.Page
+	for _, p := range reader.Manifest.Pages {
+		if p.Segment != segment || p.Page != page {
+			pages = append(pages, p)
+		}
+	}
+	if len(pages) == len(reader.Manifest.Pages) {
+		t.Fatalf("%s page %d not in the archive", segment, page)
+	}
+	reader.Manifest.Pages = pages
+	data, err := json.Marshal(reader.Manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, archive.ManifestFile), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFixture(t *testing.T, exchange string) []json.RawMessage {
+
+	t.Helper()
+	var records []json.RawMessage
+	data, err := os.ReadFile(filepath.Join(fixtures, exchange+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestGatesStopTruncatedLoad(t *testing.T) {
+
+	for _, mode := range []string{constants.LoadModeDirect, constants.LoadModeSwap} {
+		t.Run(mode, func(t *testing.T) {
+
+			server := newServer(t, 2)
+			first, store := newBuild(t, server)
+			first.AppConfig.Set(constants.LoadMode, mode)
+			if err := first.BackUp_AMXScripMaster(); err != nil {
+				t.Fatal(err)
+			}
+			if err := build(first); err != nil {
+				t.Fatalf("first build: %v", err)
+			}
+
+			// AMX answers with the first nse_fo page only
+			server.SetRecords("nse_fo", 0, readFixture(t, "nse_fo")[:2])
+
+			second, _ := newBuild(t, server)
+			second.AppConfig.Set(constants.LoadMode, mode)
+			second.Store = store
+			if err := second.BackUp_AMXScripMaster(); err != nil {
+				t.Fatal(err)
+			}
+			err := build(second)
+			if services.ExitCode(err) != services.ExitValidation {
+				t.Fatalf("got %v, want a validation failure", err)
+			}
+			assertCounts(t, liveCounts(t, second), expected)
+
+			second.Force = true
+			if err := build(second); err != nil {
+				t.Fatalf("forced build: %v", err)
+			}
+			want := map[string]int{"nse_cm": 3, "bse_cm": 2, "nse_fo": 2, "mcx_fo": 3}
+			assertCounts(t, liveCounts(t, second), want)
+		})
+	}
+}
+
+func TestGatesRequireUnderlyings(t *testing.T) {
+
+	var records []json.RawMessage
+	for _, r := range readFixture(t, "nse_fo") {
+		if !strings.Contains(string(r), `"BANKNIFTY"`) {
+			records = append(records, r)
+		}
+	}
+
+	for _, mode := range []string{constants.LoadModeDirect, constants.LoadModeSwap} {
+		t.Run(mode, func(t *testing.T) {
+
+			server := newServer(t, 2)
+			server.SetRecords("nse_fo", 2, records)
+
+			amx, store := newBuild(t, server)
+			amx.AppConfig.Set(constants.LoadMode, mode)
+			amx.ISBackupDone = true
+			err := build(amx)
+			if services.ExitCode(err) != services.ExitValidation || !strings.Contains(err.Error(), "BANKNIFTY") {
+				t.Fatalf("got %v, want a validation failure naming BANKNIFTY", err)
+			}
+			if store.Len() != 0 {
+				t.Errorf("%d rows loaded after a tripped gate", store.Len())
+			}
+		})
+	}
+}
+
+// quarantineTable keeps the records quarantined in table mode.
+type quarantineTable struct {
+	*memory.Store
+
+	mu      sync.Mutex
+	records []quarantine.Record
+}
+
+func (s *quarantineTable) Quarantine(ctx context.Context, records []quarantine.Record) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, records...)
+	return s.Store.Quarantine(ctx, records)
+}
+
+func TestQuarantineTableKeepsLoadedRunsOnly(t *testing.T) {
+
+	var records []json.RawMessage
+	for _, r := range readFixture(t, "nse_fo") {
+		if !strings.Contains(string(r), `"BANKNIFTY"`) {
+			records = append(records, r)
+		}
+	}
+
+	for _, mode := range []string{constants.LoadModeDirect, constants.LoadModeSwap} {
+		t.Run(mode, func(t *testing.T) {
+
+			server := newServer(t, 2)
+			server.SetRecords("nse_fo", 2, records)
+			amx, store := newBuild(t, server)
+			table := &quarantineTable{Store: store}
+			amx.Store = table
+			amx.AppConfig.Set(constants.LoadMode, mode)
+			amx.AppConfig.Set(constants.QuarantineMode, constants.QuarantineTable)
+			amx.ISBackupDone = true
+
+			if err := build(amx); services.ExitCode(err) != services.ExitValidation {
+				t.Fatalf("got %v, want a validation failure", err)
+			}
+			if len(table.records) != 0 {
+				t.Fatalf("%d records quarantined by a gated run", len(table.records))
+			}
+
+			server.SetRecords("nse_fo", 2, readFixture(t, "nse_fo"))
+			if err := build(amx); err != nil {
+				t.Fatalf("build: %v", err)
+			}
+			if len(table.records) == 0 {
+				t.Fatal("no records quarantined by a loaded run")
+			}
+			for _, r := range table.records {
+				if r.RunID != amx.RunID {
+					t.Errorf("record of run %s quarantined, want %s", r.RunID, amx.RunID)
+				}
+			}
+		})
+	}
+}
+
+// stagingClock records when the first nse_fo page reached the bulk table.
+type stagingClock struct {
+	*memory.Store
+
+	mu    sync.Mutex
+	first time.Time
+}
+
+func (s *stagingClock) BulkDerivative(ctx context.Context, scrips []entities.DerivativeScrip) (int64, error) {
+
+	s.mu.Lock()
+	if s.first.IsZero() && len(scrips) > 0 && scrips[0].SegmentID == "2" {
+		s.first = time.Now()
+	}
+	s.mu.Unlock()
+	return s.Store.BulkDerivative(ctx, scrips)
+}
+
+func TestSwapStagesPagesAsTheyArrive(t *testing.T) {
+
+	server := newServer(t, 2)
+	server.Slow("nse_fo", 4, 500*time.Millisecond)
+
+	amx, store := newBuild(t, server)
+	clock := &stagingClock{Store: store}
+	amx.Store = clock
+	amx.AppConfig.Set(constants.LoadMode, constants.LoadModeSwap)
+	amx.ISBackupDone = true
+
+	if err := build(amx); err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	done := time.Now()
+	assertCounts(t, liveCounts(t, amx), expected)
+
+	if clock.first.IsZero() {
+		t.Fatal("no nse_fo page was bulk copied")
+	}
+	if ahead := done.Sub(clock.first); ahead < 400*time.Millisecond {
+		t.Errorf("first nse_fo page staged %v before the build finished, want it staged while the last page was slow", ahead)
+	}
+}
+
+func TestBuildsRunConcurrently(t *testing.T) {
+
+	builds := make([]*services.AMXConfig, 2)
+	stores := make([]*memory.Store, 2)
+	for i := range builds {
+		builds[i], stores[i] = newBuild(t, newServer(t, 1))
+		builds[i].ISBackupDone = true
+	}
+
+	errs := make(chan error, len(builds))
+	for _, amx := range builds {
+		go func(amx *services.AMXConfig) { errs <- build(amx) }(amx)
+	}
+	for range builds {
+		if err := <-errs; err != nil {
+			t.Fatalf("build: %v", err)
+		}
+	}
+	for _, amx := range builds {
+		assertCounts(t, liveCounts(t, amx), expected)
+	}
+}
+
+func TestBuildStopsWhenCancelled(t *testing.T) {
+
+	server := newServer(t, 2)
+	server.Slow("nse_fo", 2, time.Minute)
+
+	amx, store := newBuild(t, server)
+	amx.ISBackupDone = true
+	token, err := amx.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err = amx.Build(ctx, token)
+	if services.ExitCode(err) != services.ExitAPI || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want an API failure from the deadline", err)
+	}
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("build returned %v after the deadline", elapsed)
+	}
+	if store.Len() != 0 {
+		t.Errorf("%d rows loaded by a cancelled build", store.Len())
+	}
+}
+
+const stockMaster = `{"status": true, "message": "Success", "data": {"stock_master": [
+	{"sid": "RELIANCE", "isin": "INE002A01018"},
+	{"sid": 1234, "isin": "INE467B01029"},
+	{"sid": null, "isin": "INE009A01021"},
+	{"sid": "SBIN", "isin": "INE062A01020"},
+	{"sid": "null", "isin": "null"}
+]}}`
+
+// withMojo points the build at a fake Mojo stock master answering body.
+func withMojo(t *testing.T, amx *services.AMXConfig, status int, body string) {
+
+	t.Helper()
+	mojo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(mojo.Close)
+
+	amx.UrlConfig.Set(amx.AppConfig.GetString(constants.Env)+"."+constants.StockMasterUrl, mojo.URL)
+	amx.AppConfig.Set(constants.MojoMaxRetries, 1)
+	amx.AppConfig.Set(constants.MojoBackoffBase, "1ms")
+	if err := amx.Init(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunBuildUpdatesStockIDs(t *testing.T) {
+
+	server := newServer(t, 2)
+	amx, _ := newBuild(t, server)
+	withMojo(t, amx, http.StatusOK, stockMaster)
+
+	if err := amx.Run_Build(context.Background()); err != nil {
+		t.Fatalf("run build: %v", err)
+	}
+	assertCounts(t, liveCounts(t, amx), expected)
+	if _, err := os.Stat(amx.AppConfig.GetString(constants.BuildMarker)); err != nil {
+		t.Errorf("build marker: %v", err)
+	}
+
+	report, err := amx.Update_StockIDs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := services.StockIDReport{Received: 5, Skipped: 1, Matched: 3, Unmatched: 1, Updated: 3}
+	if report != want {
+		t.Errorf("report %+v, want %+v", report, want)
+	}
+}
+
+func TestRunBuildKeepsMasterOnMojoFailure(t *testing.T) {
+
+	for name, tc := range map[string]struct {
+		status int
+		body   string
+	}{
+		"failure message": {http.StatusOK, `{"status": false, "message": "Failure", "data": null}`},
+		"missing message": {http.StatusOK, `{"data": {"stock_master": []}}`},
+		"server error":    {http.StatusInternalServerError, `oops`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := newServer(t, 2)
+			amx, _ := newBuild(t, server)
+			withMojo(t, amx, tc.status, tc.body)
+
+			err := amx.Run_Build(context.Background())
+			var stepErr, cause *services.StepError
+			if services.ExitCode(err) != services.ExitPostLoad || !errors.As(err, &stepErr) || !errors.As(stepErr.Err, &cause) || cause.Class != services.APIFailure {
+				t.Fatalf("got %v, want a post load failure of the API", err)
+			}
+			// the loaded master is kept
+			assertCounts(t, liveCounts(t, amx), expected)
+		})
+	}
+}
+
+// brokenStockIDs fails every stock id but the first, like a stock id
+// procedure that fails per row.
+type brokenStockIDs struct {
+	*memory.Store
+}
+
+func (s brokenStockIDs) UpdateStockIDs(ctx context.Context, ids []persistance.StockID) (persistance.StockIDUpdate, error) {
+
+	if len(ids) == 0 {
+		return persistance.StockIDUpdate{}, nil
+	}
+	result, err := s.Store.UpdateStockIDs(ctx, ids[:1])
+	result.Failed = len(ids) - 1
+	return result, err
+}
+
+func TestRunBuildKeepsMasterOnStockIDFailures(t *testing.T) {
+
+	server := newServer(t, 2)
+	amx, store := newBuild(t, server)
+	withMojo(t, amx, http.StatusOK, stockMaster)
+	amx.Store = brokenStockIDs{store}
+
+	err := amx.Run_Build(context.Background())
+	if services.ExitCode(err) != services.ExitPostLoad || !strings.Contains(err.Error(), "2 of 3 stock ids") {
+		t.Fatalf("got %v, want a post load failure for 2 of 3 stock ids", err)
+	}
+	assertCounts(t, liveCounts(t, amx), expected)
+	if _, err := os.Stat(amx.AppConfig.GetString(constants.BuildMarker)); err != nil {
+		t.Errorf("build marker: %v", err)
+	}
+
+	report, err := amx.Update_StockIDs(context.Background())
+	if err == nil {
+		t.Fatal("no error for failed stock ids")
+	}
+	want := services.StockIDReport{Received: 5, Skipped: 1, Matched: 3, Unmatched: 1, Updated: 1, Failed: 2}
+	if report != want {
+		t.Errorf("report %+v, want %+v", report, want)
+	}
+}
+
+func TestFailedRunsKeepLastGoodBackUp(t *testing.T) {
+
+	server := newServer(t, 2)
+	amx, _ := newBuild(t, server)
+	withMojo(t, amx, http.StatusOK, stockMaster)
+	amx.AppConfig.Set(constants.RetentionGenerations, 2)
+	amx.AppConfig.Set(constants.RetentionDays, 0)
+
+	started := time.Now()
+	run := func(runID string, n int) error {
+		amx.RunID, amx.RunTime, amx.ISBackupDone = runID, started.Add(time.Duration(n)*time.Minute), false
+		return amx.Run_Build(context.Background())
+	}
+	generations := func() []string {
+		backUps, err := amx.List_BackUps()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, b := range backUps {
+			ids = append(ids, b.RunID)
+		}
+		return ids
+	}
+
+	for n, runID := range []string{"good-1", "good-2", "good-3"} {
+		if err := run(runID, n); err != nil {
+			t.Fatalf("%s: %v", runID, err)
+		}
+	}
+	if got, want := generations(), []string{"good-3", "good-2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("backups %v after good runs, want %v", got, want)
+	}
+
+	server.Fail("nse_fo", 1, http.StatusInternalServerError, 0)
+	for n, runID := range []string{"failed-1", "failed-2", "failed-3"} {
+		if err := run(runID, 3+n); services.ExitCode(err) != services.ExitAPI {
+			t.Fatalf("%s: got %v, want an API failure", runID, err)
+		}
+	}
+	if got, want := generations(), []string{"failed-3", "failed-2", "failed-1", "good-3", "good-2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("backups %v after failed runs, want %v", got, want)
+	}
+
+	if err := amx.Restore_AMXScripMaster("all", "good-3"); err != nil {
+		t.Fatalf("restore good-3: %v", err)
+	}
+	assertCounts(t, liveCounts(t, amx), expected)
+}
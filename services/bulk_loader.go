@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"main.go/persistance"
+)
+
+// Load_Bulk copies the derivatives of the given segments into the bulk
+// table and moves them into the target with one merge.
+func (amx *AMXConfig) Load_Bulk(ctx context.Context, parsed []*ParsedSegment, target persistance.Target) error {
+
+	if len(parsed) == 0 {
+		return nil
+	}
+
+	if err := amx.Store.PrepareBulk(ctx); err != nil {
+		return dbError("bulk load", "Bulk table preparation failed", err)
+	}
+
+	started := time.Now()
+	var total int64
+	for _, segment := range parsed {
+
+		segStarted := time.Now()
+		copied, cErr := amx.Store.BulkDerivative(ctx, segment.Derivatives)
+		if cErr != nil {
+			return dbError("bulk load", segment.Segment+" - Bulk copy failed", cErr)
+		}
+		total += copied
+		logRate(segment.Segment, copied, time.Since(segStarted))
+	}
+
+	mergeStarted := time.Now()
+	if err := amx.Store.MergeBulk(ctx, target, amx.RunID); err != nil {
+		return dbError("bulk load", "Bulk merge failed", err)
+	}
+	log.Info().Dur("Elapsed", time.Since(mergeStarted)).Msg("Bulk table merged")
+
+	logRate("bulk total", total, time.Since(started))
+	return nil
+}
+
+func logRate(segment string, rows int64, elapsed time.Duration) {
+
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(rows) / elapsed.Seconds()
+	}
+	log.Info().Str("Segment", segment).Int64("Rows", rows).Dur("Elapsed", elapsed).Float64("Rows/sec", rate).Msg("Bulk copy completed")
+}
+
+// splitBulk separates the segments marked bulk in the registry.
+func (amx *AMXConfig) splitBulk(parsed []*ParsedSegment) (rowwise, bulk []*ParsedSegment) {
+
+	for _, segment := range parsed {
+		if seg, ok := amx.registry.Get(segment.Segment); ok && seg.Bulk {
+			bulk = append(bulk, segment)
+		} else {
+			rowwise = append(rowwise, segment)
+		}
+	}
+	return rowwise, bulk
+}
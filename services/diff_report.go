@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"main.go/constants"
+	"main.go/diff"
+	"main.go/persistance"
+)
+
+// Snapshot_Master reads the diff fields of the live master before it is
+// replaced. A failure only disables the diff report of this run.
+func (amx *AMXConfig) Snapshot_Master() ([]diff.Record, error) {
+
+	records, err := amx.Store.Snapshot(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	names := amx.segmentNames()
+	for i := range records {
+		if segment := names[records[i].Segment]; segment != "" {
+			records[i].Segment = segment
+		}
+	}
+	return records, nil
+}
+
+// previousMaster snapshots the live master for the diff report and the
+// drop gate when either is enabled. snapshot reports whether previous was
+// read. The dry run never reads the database, so neither is done.
+func (amx *AMXConfig) previousMaster() (previous []diff.Record, snapshot, diffEnabled bool) {
+
+	if amx.DryRun {
+		return nil, false, false
+	}
+	diffEnabled = amx.AppConfig.GetBool(constants.DiffReport)
+	if !diffEnabled && amx.AppConfig.GetFloat64(constants.GatesMaxDrop) <= 0 {
+		return nil, false, false
+	}
+
+	previous, err := amx.Snapshot_Master()
+	if err != nil {
+		log.Warn().Err(err).Msg("Unable to snapshot the live master, diff report and drop gate skipped")
+		return nil, false, false
+	}
+	return previous, true, diffEnabled
+}
+
+// Write_Diff compares the previous master with the current one and writes
+// the report under report_path/<run id>.
+func (amx *AMXConfig) Write_Diff(previous, current []diff.Record) error {
+
+	report := diff.Compare(amx.RunID, previous, current)
+	dir := filepath.Join(amx.AppConfig.GetString(constants.ReportPath), amx.RunID)
+
+	if err := report.Write(dir); err != nil {
+		return fileError("diff report", "Unable to write diff report to "+dir, err)
+	}
+
+	for _, seg := range report.Segments {
+		log.Info().Str("Segment", seg.Segment).Int("Added", len(seg.Added)).Int("Removed", len(seg.Removed)).Int("Modified", len(seg.Modified)).Msg("Scrip master diff")
+	}
+	log.Info().Str("Path", dir).Msg("Diff report written")
+	return nil
+}
+
+func masterRecords(parsed []*ParsedSegment) []diff.Record {
+
+	var records []diff.Record
+	for _, segment := range parsed {
+		for _, eq := range segment.Equity {
+			records = append(records, diffRecord(segment.Segment, persistance.EquityValues(eq)))
+		}
+		for _, derv := range segment.Derivatives {
+			records = append(records, diffRecord(segment.Segment, persistance.DerivativeValues(derv)))
+		}
+	}
+	return records
+}
+
+func diffRecord(segment string, values map[string]string) diff.Record {
+
+	return diff.Record{
+		TokenMktID: values["tokenMktId"],
+		Segment:    segment,
+		Symbol:     values["symbolName"],
+		LotSize:    values["minimumLot"],
+		TickSize:   values["priceTick"],
+		FreezeQty:  values["freezePercent"],
+		ISIN:       values["isinCode"],
+	}
+}
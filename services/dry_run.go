@@ -0,0 +1,43 @@
+package services
+
+import (
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"main.go/constants"
+	"main.go/persistance"
+	"main.go/persistance/dryrun"
+)
+
+// Load_DryRun writes the procedure calls the load would make to JSONL and
+// CSV files under dry_run_path/<run id>.
+// It never opens a database connection.
+func (amx *AMXConfig) Load_DryRun(parsed []*ParsedSegment) error {
+
+	dir := filepath.Join(amx.AppConfig.GetString(constants.DryRunPath), amx.RunID)
+	writer, err := dryrun.NewWriter(dir)
+	if err != nil {
+		return fileError("dry run", "Unable to create dry run output in "+dir, err)
+	}
+
+	procs := amx.MSSQLEntities.Procedures()
+	for _, segment := range parsed {
+		for _, eq := range segment.Equity {
+			if cErr := writer.Call(procs.EQInsert, persistance.EquityValues(eq)); cErr != nil {
+				log.Error().Str("Procedure", procs.EQInsert.Name).Str("Token", eq.TokenMktID).Err(cErr).Msg("Dry run call failed")
+			}
+		}
+		for _, derv := range segment.Derivatives {
+			if cErr := writer.Call(procs.DervInsert, persistance.DerivativeValues(derv)); cErr != nil {
+				log.Error().Str("Procedure", procs.DervInsert.Name).Str("Token", derv.TokenMktID).Err(cErr).Msg("Dry run call failed")
+			}
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return fileError("dry run", "Unable to write dry run output in "+dir, err)
+	}
+
+	log.Info().Str("Path", dir).Interface("Calls", writer.Calls()).Msg("Dry run completed, database untouched")
+	return nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog/log"
+	"main.go/internal/httpapi"
+)
+
+// ErrorClass tells who has to act on a failure.
+type ErrorClass int
+
+const (
+	APIFailure ErrorClass = iota + 1
+	DBFailure
+	ValidationFailure
+	// FileFailure is an output file, such as a report or the quarantine,
+	// that could not be written.
+	FileFailure
+	// PostLoadFailure is a step that failed after the new master was
+	// loaded and kept, it wraps the *StepError of that step.
+	PostLoadFailure
+)
+
+func (c ErrorClass) String() string {
+
+	switch c {
+	case APIFailure:
+		return "API"
+	case DBFailure:
+		return "DB"
+	case ValidationFailure:
+		return "Validation"
+	case FileFailure:
+		return "File"
+	case PostLoadFailure:
+		return "PostLoad"
+	default:
+		return "Unknown"
+	}
+}
+
+// Exit codes of the process, one per failure class so the scheduler can
+// tell an AMX outage from a database or data problem.
+const (
+	ExitOK         = 0
+	ExitFailure    = 1
+	ExitAPI        = 2
+	ExitDB         = 3
+	ExitValidation = 4
+	ExitPostLoad   = 5
+	ExitFile       = 6
+)
+
+// StepError is returned by the pipeline steps.
+type StepError struct {
+	Class   ErrorClass
+	Step    string
+	Details string
+	Url     string
+	Err     error
+}
+
+func (e *StepError) Error() string {
+
+	msg := e.Step + ": " + e.Details
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+func apiError(step, details string, err error) error {
+
+	stepErr := &StepError{Class: APIFailure, Step: step, Details: details, Err: err}
+	var apiErr *httpapi.Error
+	if errors.As(err, &apiErr) {
+		stepErr.Url = apiErr.URL
+	}
+	return stepErr
+}
+
+func dbError(step, details string, err error) error {
+	return &StepError{Class: DBFailure, Step: step, Details: details, Err: err}
+}
+
+func validationError(step, details string, err error) error {
+	return &StepError{Class: ValidationFailure, Step: step, Details: details, Err: err}
+}
+
+func fileError(step, details string, err error) error {
+	return &StepError{Class: FileFailure, Step: step, Details: details, Err: err}
+}
+
+// postLoadError reports the failure of a step that runs on the loaded
+// master, which is kept rather than rolled back.
+func postLoadError(err error) error {
+
+	stepErr := &StepError{Class: PostLoadFailure, Step: "post load", Details: "Master loaded, a following step failed", Err: err}
+	var failed *StepError
+	if errors.As(err, &failed) {
+		stepErr.Step, stepErr.Url = failed.Step, failed.Url
+	}
+	return stepErr
+}
+
+// ExitCode maps the error returned by a command to the process exit code.
+func ExitCode(err error) int {
+
+	if err == nil {
+		return ExitOK
+	}
+
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) {
+		return ExitFailure
+	}
+	switch stepErr.Class {
+	case APIFailure:
+		return ExitAPI
+	case DBFailure:
+		return ExitDB
+	case ValidationFailure:
+		return ExitValidation
+	case FileFailure:
+		return ExitFile
+	case PostLoadFailure:
+		return ExitPostLoad
+	default:
+		return ExitFailure
+	}
+}
+
+// LogStatus reports the outcome of a command.
+func LogStatus(command string, err error) {
+
+	if err == nil {
+		log.Info().Str("Command", command).Msg("Completed successfully")
+		return
+	}
+
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) {
+		log.Error().Str("Command", command).Err(err).Msg("Command failed")
+		return
+	}
+
+	event := log.Error().Stack()
+	message := "Command failed"
+	contact := stepErr
+	if stepErr.Class == PostLoadFailure {
+		event = log.Warn()
+		message = "Command completed with a failed post load step"
+		errors.As(stepErr.Err, &contact)
+	}
+
+	event = event.Str("Command", command).Str("Step", stepErr.Step).Str("Class", stepErr.Class.String()).Str("Details", stepErr.Details)
+	switch contact.Class {
+	case APIFailure:
+		event = event.Str("Contact", "API Team").Str("Url", contact.Url)
+	case DBFailure:
+		event = event.Str("Contact", "MSIL Team")
+	}
+	event.Err(stepErr.Err).Msg(message)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"main.go/constants"
+	"main.go/export"
+	"main.go/persistance"
+)
+
+// Export_Parsed writes the scrips built by this run to export.path/<run id>.
+func (amx *AMXConfig) Export_Parsed(parsed []*ParsedSegment) error {
+
+	var rows []export.Row
+	for _, segment := range parsed {
+		for _, eq := range segment.Equity {
+			rows = append(rows, export.FromValues(segment.Segment, persistance.EquityValues(eq)))
+		}
+		for _, derv := range segment.Derivatives {
+			rows = append(rows, export.FromValues(segment.Segment, persistance.DerivativeValues(derv)))
+		}
+	}
+	return amx.writeExport(rows)
+}
+
+// Export_Master writes the current database contents of the master.
+func (amx *AMXConfig) Export_Master() error {
+
+	rows, err := amx.Read_Master()
+	if err != nil {
+		return dbError("export", "Export query failed", err)
+	}
+	return amx.writeExport(rows)
+}
+
+// Read_Master reads the live master as export rows.
+func (amx *AMXConfig) Read_Master() ([]export.Row, error) {
+
+	master, err := amx.Store.ReadMaster(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	names := amx.segmentNames()
+	for _, row := range master {
+		if row["segment"] == "" {
+			row["segment"] = names[row["segmentId"]]
+		}
+	}
+	return master, nil
+}
+
+func (amx *AMXConfig) writeExport(rows []export.Row) error {
+
+	opts := export.Options{
+		Dir:        filepath.Join(amx.AppConfig.GetString(constants.ExportPath), amx.RunID),
+		Formats:    strings.Split(amx.AppConfig.GetString(constants.ExportFormats), ","),
+		PerSegment: amx.AppConfig.GetBool(constants.ExportPerSegment),
+		Gzip:       amx.AppConfig.GetBool(constants.ExportGzip),
+		Manifest:   amx.AppConfig.GetBool(constants.ExportManifest),
+	}
+
+	manifest, err := export.Write(opts, rows)
+	if err != nil {
+		return fileError("export", "Unable to write export to "+opts.Dir, err)
+	}
+	for _, f := range manifest.Files {
+		log.Info().Str("File", f.Name).Int("Rows", f.Rows).Str("SHA256", f.SHA256).Msg("Exported")
+	}
+	log.Info().Str("Path", opts.Dir).Int("Rows", len(rows)).Msg("Scrip master export completed")
+	return nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"os"
+
+	"github.com/rs/zerolog/log"
+	"main.go/constants"
+	"main.go/diff"
+	"main.go/gates"
+)
+
+// Check_Gates runs the sanity gates over the tallies of the parsed segments
+// before the master is touched. previous is the live master when snapshot
+// is set. A tripped gate fails the run unless Force is set.
+func (amx *AMXConfig) Check_Gates(cfg gates.Config, tallies []*Tally, previous []diff.Record, snapshot bool) error {
+
+	live := make(map[string]int)
+	for _, r := range previous {
+		live[r.Segment]++
+	}
+
+	segments := make([]gates.Segment, 0, len(tallies))
+	for _, t := range tallies {
+		s := gates.Segment{
+			Code:     t.Segment,
+			Received: t.Received,
+			Skipped:  t.Skipped,
+			Rows:     t.Rows,
+			Previous: -1,
+			Symbols:  t.Symbols,
+		}
+		if snapshot {
+			s.Previous = live[t.Segment]
+		}
+		segments = append(segments, s)
+	}
+
+	report := gates.Check(cfg, segments)
+	if report.Passed() {
+		log.Info().Int("Segments", len(segments)).Msg("Sanity gates passed")
+		return nil
+	}
+
+	report.Write(os.Stdout)
+	if amx.Force {
+		log.Warn().Err(report.Err()).Msg("Sanity gates tripped, loading anyway because of --force")
+		return nil
+	}
+	log.Error().Err(report.Err()).Msg("Sanity gates tripped, master left untouched")
+	return validationError("gates", "Sanity gates tripped, master left untouched, rerun with --force to load anyway", report.Err())
+}
+
+// gateConfig reads the gate limits, the required underlyings are also
+// what the tallies look for.
+func (amx *AMXConfig) gateConfig() (gates.Config, error) {
+
+	cfg := gates.Config{
+		MaxDropPercent: amx.AppConfig.GetFloat64(constants.GatesMaxDrop),
+		MaxSkipPercent: amx.AppConfig.GetFloat64(constants.GatesMaxSkip),
+	}
+	if err := amx.AppConfig.UnmarshalKey(constants.GatesMinRows, &cfg.MinRows); err != nil {
+		return cfg, err
+	}
+	if err := amx.AppConfig.UnmarshalKey(constants.GatesUnderlyings, &cfg.RequiredUnderlyings); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"main.go/constants"
+	"main.go/gates"
+	"main.go/persistance"
+	"main.go/segments"
+)
+
+// Load_Direct clears the live master and inserts the parsed segments into it.
+func (amx *AMXConfig) Load_Direct(ctx context.Context, parsed []*ParsedSegment) error {
+
+	if err := amx.Delete_Records(ctx, constants.AssetEquity); err != nil {
+		return err
+	}
+	if err := amx.Delete_Records(ctx, constants.AssetDerivative); err != nil {
+		return err
+	}
+
+	if err := amx.insertSegments(ctx, parsed, persistance.Live); err != nil {
+		return err
+	}
+	if err := amx.Store.Flush(ctx); err != nil {
+		log.Error().Err(err).Msg("Error in flushing the scrip master")
+		return dbError("load", "Scrip master flush failed", err)
+	}
+	amx.clearAffected()
+	return nil
+}
+
+// loadSwap writes the parsed pages of source into the shadow tables while
+// the later pages are still fetched, the derivatives of the segments marked
+// bulk through the bulk table. Once every page is staged the gates and the
+// staging validation run on the tallies, and the shadow tables are switched
+// in with a single transaction. The live master is left untouched until the
+// swap, so readers never see a partial load and a failed or gated run
+// changes nothing.
+func (amx *AMXConfig) loadSwap(ctx context.Context, source pageSource, cfg gates.Config) error {
+
+	if err := amx.Store.PrepareStaging(ctx); err != nil {
+		log.Error().Err(err).Msg("Error in preparing staging tables")
+		return dbError("load", "Staging preparation failed", err)
+	}
+	bulk := amx.registry.HasBulk()
+	if bulk {
+		if err := amx.Store.PrepareBulk(ctx); err != nil {
+			return dbError("bulk load", "Bulk table preparation failed", err)
+		}
+	}
+	log.Info().Msg("Staging tables prepared")
+
+	// the rejected records are kept once the swap committed
+	q := amx.openQuarantine()
+	defer q.abort()
+
+	tallies, err := amx.streamPages(ctx, source, cfg.RequiredUnderlyings, func(ctx context.Context, seg segments.Segment, index int, parsed *ParsedSegment) error {
+		q.add(ctx, parsed.Rejected)
+		return amx.stagePage(ctx, seg, parsed)
+	})
+	if err != nil {
+		return err
+	}
+
+	if bulk {
+		started := time.Now()
+		if err := amx.Store.MergeBulk(ctx, persistance.Staging, amx.RunID); err != nil {
+			return dbError("bulk load", "Bulk merge failed", err)
+		}
+		log.Info().Dur("Elapsed", time.Since(started)).Msg("Bulk table merged")
+	}
+	for _, t := range tallies {
+		log.Info().Str("Segment", t.Segment).Int("Inserted Count", t.Rows).Msg(t.Segment + " has been staged")
+	}
+
+	Write_Summary(os.Stdout, tallies)
+
+	previous, snapshot, diffEnabled := amx.previousMaster()
+	if err := amx.Check_Gates(cfg, tallies, previous, snapshot); err != nil {
+		return err
+	}
+
+	if err := amx.validateStaging(ctx, tallies); err != nil {
+		log.Error().Err(err).Msg("Staged scrip master is not consistent, live master left untouched")
+		return validationError("load", "Staging validation failed", err)
+	}
+	log.Info().Msg("Staged scrip master validated")
+
+	if err := amx.Store.SwapStaging(ctx); err != nil {
+		return dbError("load", "Swap transaction rolled back", err)
+	}
+	log.Info().Msg("Staged scrip master switched in")
+
+	if qErr := q.close(ctx); qErr != nil {
+		log.Error().Err(qErr).Msg("Unable to quarantine rejected records")
+	}
+
+	// the rows of the run are only in the database now
+	if amx.AppConfig.GetBool(constants.ExportEnabled) {
+		if err := amx.Export_Master(); err != nil {
+			log.Error().Err(err).Msg("Scrip master export failed")
+		}
+	}
+	if diffEnabled {
+		current, sErr := amx.Snapshot_Master()
+		if sErr == nil {
+			sErr = amx.Write_Diff(previous, current)
+		}
+		if sErr != nil {
+			log.Error().Err(sErr).Msg("Unable to write diff report")
+		}
+	}
+	return nil
+}
+
+// stagePage writes a parsed page into the shadow tables, or its
+// derivatives into the bulk table when its segment is marked bulk.
+func (amx *AMXConfig) stagePage(ctx context.Context, seg segments.Segment, parsed *ParsedSegment) error {
+
+	if seg.Bulk {
+		if _, err := amx.Store.BulkDerivative(ctx, parsed.Derivatives); err != nil {
+			return dbError("bulk load", seg.Code+" - Bulk copy failed", err)
+		}
+		return nil
+	}
+
+	if err := amx.Store.UpsertEquity(ctx, persistance.Staging, parsed.Equity); err != nil {
+		return dbError("load", seg.Code+" - Query execution failed", err)
+	}
+	if err := amx.Store.UpsertDerivative(ctx, persistance.Staging, parsed.Derivatives); err != nil {
+		return dbError("load", seg.Code+" - Query execution failed", err)
+	}
+	return nil
+}
+
+// insertSegments loads the segments on up to pipeline.loaders workers,
+// each segment on its own connection and the segments marked bulk together
+// through the bulk table, and returns the first failure.
+func (amx *AMXConfig) insertSegments(ctx context.Context, parsed []*ParsedSegment, target persistance.Target) error {
+
+	rowwise, bulk := amx.splitBulk(parsed)
+
+	jobs := make([]func(context.Context) error, 0, len(rowwise)+1)
+	for _, segment := range rowwise {
+		segment := segment
+		jobs = append(jobs, func(ctx context.Context) error {
+			return amx.Insert_Records(ctx, segment, target)
+		})
+	}
+	jobs = append(jobs, func(ctx context.Context) error {
+		return amx.Load_Bulk(ctx, bulk, target)
+	})
+
+	if err := runWorkers(ctx, amx.AppConfig.GetInt(constants.PipelineLoaders), jobs); err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return dbError("load", "Load cancelled", err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (amx *AMXConfig) Insert_Records(ctx context.Context, segment *ParsedSegment, target persistance.Target) error {
+
+	if err := amx.Store.UpsertEquity(ctx, target, segment.Equity); err != nil {
+		return dbError("load", segment.Segment+" - Query execution failed", err)
+	}
+	if err := amx.Store.UpsertDerivative(ctx, target, segment.Derivatives); err != nil {
+		return dbError("load", segment.Segment+" - Query execution failed", err)
+	}
+
+	log.Info().Str("Segment", segment.Segment).Int("Inserted Count", len(segment.Equity)+len(segment.Derivatives)).Msg(segment.Segment + " has been loaded")
+	return nil
+}
+
+// validateStaging compares the row count per market segment in the shadow
+// tables with the number of scrips that were written to them.
+func (amx *AMXConfig) validateStaging(ctx context.Context, tallies []*Tally) error {
+
+	expected := make(map[string]int)
+	for _, t := range tallies {
+		for id, n := range t.Markets {
+			expected[id] += n
+		}
+	}
+	if len(expected) == 0 {
+		return fmt.Errorf("no scrips were staged")
+	}
+
+	staged, err := amx.Store.StagedCounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(expected))
+	for id := range expected {
+		ids = append(ids, id)
+	}
+	for id := range staged {
+		if _, ok := expected[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if staged[id] != expected[id] {
+			return fmt.Errorf("market segment %s: staged %d rows, expected %d", id, staged[id], expected[id])
+		}
+	}
+	return nil
+}
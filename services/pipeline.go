@@ -0,0 +1,327 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"main.go/constants"
+	"main.go/segments"
+)
+
+// page is one fetched or archived page, index is its position within the
+// pages of its segment.
+type page struct {
+	segment int
+	index   int
+	records []json.RawMessage
+}
+
+// parsedPage is a page after the parsers.
+type parsedPage struct {
+	segment int
+	index   int
+	parsed  *ParsedSegment
+}
+
+// pageSource reads the pages of a segment in order and hands each to emit.
+// emit fails once the pipeline is cancelled.
+type pageSource func(ctx context.Context, seg segments.Segment, emit func(records []json.RawMessage) error) error
+
+// pageSink takes the parsed pages as they arrive, index is the position of
+// the page within its segment. It is called from up to pipeline.loaders
+// goroutines at once.
+type pageSink func(ctx context.Context, seg segments.Segment, index int, parsed *ParsedSegment) error
+
+// Tally is the running count of one segment, added up page by page so the
+// summary, the gates and the staging validation never need its rows.
+type Tally struct {
+	Segment  string
+	Received int
+	Skipped  int
+	Rows     int
+	// Reasons is the skip count per reason.
+	Reasons map[string]int
+	// Symbols holds the required underlyings of the segment that were seen.
+	Symbols map[string]bool
+	// Markets is the row count per market segment id.
+	Markets map[string]int
+
+	required map[string]bool
+}
+
+func newTally(segment string, required []string) *Tally {
+
+	t := &Tally{Segment: segment, Reasons: make(map[string]int), Symbols: make(map[string]bool), Markets: make(map[string]int)}
+	t.required = make(map[string]bool, len(required))
+	for _, symbol := range required {
+		t.required[symbol] = true
+	}
+	return t
+}
+
+func (t *Tally) add(p *ParsedSegment) {
+
+	t.Received += p.Count
+	t.Skipped += p.SkipCount
+	for reason, n := range p.Skipped {
+		t.Reasons[reason] += n
+	}
+	t.Rows += len(p.Equity) + len(p.Derivatives)
+	for _, eq := range p.Equity {
+		t.Markets[eq.SegmentID]++
+		if t.required[eq.SymbolName] {
+			t.Symbols[eq.SymbolName] = true
+		}
+	}
+	for _, derv := range p.Derivatives {
+		t.Markets[derv.SegmentID]++
+		if t.required[derv.SymbolName] {
+			t.Symbols[derv.SymbolName] = true
+		}
+	}
+}
+
+// streamPages reads the enabled segments from source, up to
+// pipeline.fetchers segments at a time, parses their pages on
+// pipeline.parsers workers and hands every parsed page to sink on
+// pipeline.loaders workers. Each stage waits for the next with at most
+// pipeline.page_buffer pages, so the pages held at once are bounded by the
+// buffers and the workers, whatever the size of a segment. The tallies of
+// the segments are returned in registry order, the first failing segment or
+// sink call cancels the others.
+func (amx *AMXConfig) streamPages(ctx context.Context, source pageSource, required map[string][]string, sink pageSink) ([]*Tally, error) {
+
+	enabled := amx.registry.Enabled()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once   sync.Once
+		failed error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			failed = err
+			cancel()
+		})
+	}
+
+	buffer := atLeast(amx.AppConfig.GetInt(constants.PipelinePageBuffer), 0)
+	pages := make(chan page, buffer)
+	parsed := make(chan parsedPage, buffer)
+	slots := make(chan struct{}, atLeast(amx.AppConfig.GetInt(constants.PipelineFetchers), 1))
+
+	var fetchers sync.WaitGroup
+	for index, seg := range enabled {
+		fetchers.Add(1)
+		go func(index int, seg segments.Segment) {
+			defer fetchers.Done()
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			n := 0
+			err := source(ctx, seg, func(records []json.RawMessage) error {
+				select {
+				case pages <- page{segment: index, index: n, records: records}:
+					n++
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			})
+			if err != nil {
+				fail(err)
+			}
+		}(index, seg)
+	}
+	go func() {
+		fetchers.Wait()
+		close(pages)
+	}()
+
+	var parsers sync.WaitGroup
+	for i := atLeast(amx.AppConfig.GetInt(constants.PipelineParsers), 1); i > 0; i-- {
+		parsers.Add(1)
+		go func() {
+			defer parsers.Done()
+			for p := range pages {
+				if ctx.Err() != nil {
+					continue // draining after a failure
+				}
+
+				seg := enabled[p.segment]
+				var result *ParsedSegment
+				if seg.IsEquity() {
+					result = amx.Parse_EQ(p.records, seg)
+				} else {
+					result = amx.Parse_Derv(p.records, seg)
+				}
+
+				select {
+				case parsed <- parsedPage{segment: p.segment, index: p.index, parsed: result}:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		parsers.Wait()
+		close(parsed)
+	}()
+
+	tallies := make([]*Tally, len(enabled))
+	pageCounts := make([]int, len(enabled))
+	for index, seg := range enabled {
+		tallies[index] = newTally(seg.Code, required[seg.Code])
+	}
+
+	var (
+		mu      sync.Mutex
+		loaders sync.WaitGroup
+	)
+	for i := atLeast(amx.AppConfig.GetInt(constants.PipelineLoaders), 1); i > 0; i-- {
+		loaders.Add(1)
+		go func() {
+			defer loaders.Done()
+			for p := range parsed {
+				if ctx.Err() != nil {
+					continue // draining after a failure
+				}
+
+				mu.Lock()
+				tallies[p.segment].add(p.parsed)
+				pageCounts[p.segment]++
+				mu.Unlock()
+
+				if err := sink(ctx, enabled[p.segment], p.index, p.parsed); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+	loaders.Wait()
+
+	if failed != nil {
+		return nil, failed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, apiError("fetch", "Build cancelled", err)
+	}
+
+	for index, t := range tallies {
+		log.Info().Str("Segment", t.Segment).Int("Pages", pageCounts[index]).Int("Processed Count", t.Received).Int("Skipped Count", t.Skipped).Interface("Skip Reasons", t.Reasons).Msg(t.Segment + " has been parsed")
+	}
+	return tallies, nil
+}
+
+// parsePages streams the pages of source and keeps every parsed page. The
+// parsed segments are returned whole, so their rows are all held until they
+// are loaded.
+func (amx *AMXConfig) parsePages(ctx context.Context, source pageSource, required map[string][]string) ([]*ParsedSegment, []*Tally, error) {
+
+	var mu sync.Mutex
+	pages := make(map[string][]*ParsedSegment)
+
+	tallies, err := amx.streamPages(ctx, source, required, func(ctx context.Context, seg segments.Segment, index int, parsed *ParsedSegment) error {
+
+		mu.Lock()
+		defer mu.Unlock()
+		for len(pages[seg.Code]) <= index {
+			pages[seg.Code] = append(pages[seg.Code], nil)
+		}
+		pages[seg.Code][index] = parsed
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parsed := make([]*ParsedSegment, len(tallies))
+	for index, t := range tallies {
+		parsed[index] = mergePages(t.Segment, pages[t.Segment])
+		delete(pages, t.Segment)
+	}
+	return parsed, tallies, nil
+}
+
+// mergePages joins the parsed pages of a segment.
+func mergePages(segment string, pages []*ParsedSegment) *ParsedSegment {
+
+	result := &ParsedSegment{Segment: segment}
+	for _, p := range pages {
+		result.Count += p.Count
+		result.SkipCount += p.SkipCount
+		for reason, n := range p.Skipped {
+			if result.Skipped == nil {
+				result.Skipped = make(map[string]int)
+			}
+			result.Skipped[reason] += n
+		}
+		result.Equity = append(result.Equity, p.Equity...)
+		result.Derivatives = append(result.Derivatives, p.Derivatives...)
+		result.Rejected = append(result.Rejected, p.Rejected...)
+	}
+	return result
+}
+
+// runWorkers runs the jobs on up to workers goroutines and returns the
+// first failure. The jobs not yet started are dropped after a failure or
+// once ctx is done.
+func runWorkers(ctx context.Context, workers int, jobs []func(context.Context) error) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once   sync.Once
+		failed error
+	)
+	queue := make(chan func(context.Context) error)
+
+	var wg sync.WaitGroup
+	for i := atLeast(workers, 1); i > 0; i-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				if err := job(ctx); err != nil {
+					once.Do(func() {
+						failed = err
+						cancel()
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, job := range jobs {
+		select {
+		case queue <- job:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if failed != nil {
+		return failed
+	}
+	return ctx.Err()
+}
+
+func atLeast(n, min int) int {
+
+	if n < min {
+		return min
+	}
+	return n
+}
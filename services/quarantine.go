@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"main.go/constants"
+	"main.go/persistance/quarantine"
+)
+
+// Quarantine_Rejected keeps the records skipped by the parsers for later
+// analysis, in a file or in the quarantine table as configured. A dry run
+// writes the file instead of the table.
+func (amx *AMXConfig) Quarantine_Rejected(parsed []*ParsedSegment) error {
+
+	ctx := context.Background()
+	q := amx.openQuarantine()
+	for _, segment := range parsed {
+		q.add(ctx, segment.Rejected)
+	}
+	return q.close(ctx)
+}
+
+// rejects keeps the rejected records of a run as they are parsed. They are
+// written to a temporary file, or held for the table, and only kept once
+// close is called, so a run that aborts leaves no quarantined records. The
+// first failure stops the quarantine and is returned by close, the run
+// itself goes on.
+type rejects struct {
+	amx     *AMXConfig
+	table   bool
+	path    string
+	file    *quarantine.Writer
+	pending []quarantine.Record
+
+	mu    sync.Mutex
+	count int
+	err   error
+}
+
+// openQuarantine starts keeping the rejected records of this run, it
+// returns nil when they are not kept. The methods of a nil quarantine do
+// nothing.
+func (amx *AMXConfig) openQuarantine() *rejects {
+
+	mode := amx.AppConfig.GetString(constants.QuarantineMode)
+	if mode == constants.QuarantineOff {
+		return nil
+	}
+
+	return &rejects{
+		amx:   amx,
+		table: mode == constants.QuarantineTable && !amx.DryRun,
+		path:  filepath.Join(amx.AppConfig.GetString(constants.QuarantinePath), amx.RunID, "rejected.jsonl"),
+	}
+}
+
+func (q *rejects) add(ctx context.Context, records []quarantine.Record) {
+
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(records) == 0 || q.err != nil {
+		return
+	}
+	q.count += len(records)
+
+	if q.table {
+		q.pending = append(q.pending, records...)
+		return
+	}
+
+	// the file is only created for the first rejected record
+	if q.file == nil {
+		file, err := quarantine.NewWriter(q.path)
+		if err != nil {
+			q.err = fileError("quarantine", "Unable to write "+q.path, err)
+			return
+		}
+		q.file = file
+	}
+	if err := q.file.Write(records); err != nil {
+		q.err = fileError("quarantine", "Unable to write "+q.path, err)
+	}
+}
+
+// close keeps the rejected records.
+func (q *rejects) close(ctx context.Context) error {
+
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.err != nil {
+		q.drop()
+		return q.err
+	}
+	if q.count == 0 {
+		return nil
+	}
+	if q.table {
+		records := q.pending
+		q.pending = nil
+		if err := q.amx.Store.Quarantine(ctx, records); err != nil {
+			return dbError("quarantine", "Query execution failed", err)
+		}
+		log.Info().Int("Records", q.count).Msg("Rejected records quarantined")
+		return nil
+	}
+	file := q.file
+	q.file = nil
+	if err := file.Close(); err != nil {
+		return fileError("quarantine", "Unable to write "+q.path, err)
+	}
+	log.Info().Str("Path", q.path).Int("Records", q.count).Msg("Rejected records quarantined")
+	return nil
+}
+
+// abort drops the rejected records of a failed run. It does nothing once
+// close was called.
+func (q *rejects) abort() {
+
+	if q == nil {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.drop()
+}
+
+// drop discards the records held so far. The caller holds mu.
+func (q *rejects) drop() {
+
+	q.pending = nil
+	if q.file != nil {
+		q.file.Abort()
+		q.file = nil
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"main.go/constants"
+)
+
+// Restore_AMXScripMaster puts the backed-up rows of the given asset classes
+// (Equity, Derivative or all) back into the live master. An empty generation
+// restores the latest backup.
+func (amx *AMXConfig) Restore_AMXScripMaster(assetClass, generation string) error {
+
+	classes, err := AssetClasses(assetClass)
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid asset class for restore")
+		return validationError("restore", "Restore not started", err)
+	}
+
+	if rErr := amx.restore(classes, generation); rErr != nil {
+		return dbError("restore", "Restore from backup failed", rErr)
+	}
+	return nil
+}
+
+// AssetClasses resolves the restore argument into the asset classes used by
+// the delete and restore procedures.
+func AssetClasses(assetClass string) ([]string, error) {
+
+	switch strings.ToLower(assetClass) {
+	case "", "all":
+		return []string{constants.AssetEquity, constants.AssetDerivative}, nil
+	case "equity":
+		return []string{constants.AssetEquity}, nil
+	case "derivative":
+		return []string{constants.AssetDerivative}, nil
+	default:
+		return nil, fmt.Errorf("unknown asset class %q", assetClass)
+	}
+}
+
+// restore puts the backed-up rows of the given asset classes back in one
+// transaction.
+func (amx *AMXConfig) restore(classes []string, generation string) error {
+
+	ctx := context.Background()
+
+	if generation == "" {
+		var err error
+		if generation, err = amx.latestBackUp(ctx); err != nil {
+			log.Error().Err(err).Msg("Unable to find the backup generation")
+			return err
+		}
+	}
+	log.Info().Str("Backup Generation", generation).Strs("Asset Class", classes).Msg("Restoring AMX ScripMaster from backup")
+
+	if err := amx.Store.Restore(ctx, generation, classes); err != nil {
+		return err
+	}
+
+	log.Info().Str("Backup Generation", generation).Strs("Asset Class", classes).Msg("Restore Completed...")
+	return nil
+}
+
+// markAffected records that the live rows of an asset class are being
+// replaced, so a failure before the load completes can be rolled back.
+func (amx *AMXConfig) markAffected(class string) {
+
+	amx.mu.Lock()
+	defer amx.mu.Unlock()
+
+	if amx.affected == nil {
+		amx.affected = make(map[string]bool)
+	}
+	amx.affected[class] = true
+}
+
+func (amx *AMXConfig) clearAffected() {
+
+	amx.mu.Lock()
+	defer amx.mu.Unlock()
+
+	amx.affected = nil
+}
+
+// rollback restores the affected asset classes when rollback_on_failure is
+// enabled.
+func (amx *AMXConfig) rollback() {
+
+	if !amx.AppConfig.GetBool(constants.RollbackOnFailure) || !amx.ISBackupDone {
+		return
+	}
+
+	amx.mu.Lock()
+	var classes []string
+	for _, class := range []string{constants.AssetEquity, constants.AssetDerivative} {
+		if amx.affected[class] {
+			classes = append(classes, class)
+		}
+	}
+	amx.affected = nil
+	amx.mu.Unlock()
+
+	if len(classes) == 0 {
+		return
+	}
+
+	log.Warn().Strs("Asset Class", classes).Msg("Rolling back AMX ScripMaster after failure")
+	if err := amx.restore(classes, amx.RunID); err != nil {
+		log.Error().Err(err).Strs("Asset Class", classes).Msg("Rollback failed, restore manually with the restore command")
+	}
+}
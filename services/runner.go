@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Run_Build runs the build pipeline on the pages fetched from AMX.
+func (amx *AMXConfig) Run_Build(ctx context.Context) error {
+
+	return amx.runPipeline(func() error {
+		accToken, err := amx.Login()
+		if err != nil {
+			return err
+		}
+		return amx.Build(ctx, accToken)
+	})
+}
+
+// Run_Rebuild runs the build pipeline on the pages archived by an earlier
+// run.
+func (amx *AMXConfig) Run_Rebuild(ctx context.Context, runID string) error {
+
+	if runID == "" {
+		return validationError("rebuild", "rebuild needs --from-archive <run id>", nil)
+	}
+	return amx.runPipeline(func() error {
+		return amx.Rebuild(ctx, runID)
+	})
+}
+
+// runPipeline backs the master up, loads it with build and runs the steps
+// that follow a load. A failing backup or build stops the pipeline and the
+// replaced asset classes are rolled back before the error is returned. The
+// steps after the load keep the loaded master, a failed stock id update is
+// returned as a PostLoadFailure once the run is complete. The backups
+// outside the retention are pruned once the master is loaded.
+func (amx *AMXConfig) runPipeline(build func() error) (err error) {
+
+	if amx.DryRun {
+		return build()
+	}
+
+	defer func() {
+		if err != nil {
+			amx.rollback()
+		}
+	}()
+
+	if err = amx.BackUp_AMXScripMaster(); err != nil {
+		return err
+	}
+
+	if err = build(); err != nil {
+		return err
+	}
+
+	if mErr := amx.Build_MarketCap(); mErr != nil {
+		log.Error().Err(mErr).Msg("Market cap update failed, continuing")
+	}
+
+	// the stock ids are set on the loaded master, which a failure here
+	// leaves in place: the run reports it with its own exit code
+	var stockErr error
+	if sErr := amx.UpdateStockID(); sErr != nil {
+		log.Error().Err(sErr).Msg("Stock id update failed, the loaded master is kept")
+		stockErr = postLoadError(sErr)
+	}
+
+	if mErr := amx.Mark_Build(); mErr != nil {
+		log.Error().Err(mErr).Msg("Unable to write build marker")
+	}
+
+	// only a run that loaded counts toward retention, so failing runs
+	// cannot prune the backups taken before them
+	if _, pErr := amx.pruneBackUps(context.Background()); pErr != nil {
+		log.Warn().Err(pErr).Msg("Unable to apply backup retention")
+	}
+	return stockErr
+}
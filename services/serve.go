@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"main.go/constants"
+	"main.go/lookup"
+)
+
+// Serve loads the master into memory and serves the lookup endpoints. The
+// index reloads whenever a build writes a new build marker.
+func (amx *AMXConfig) Serve(ctx context.Context) error {
+
+	server := &lookup.Server{
+		Index:    lookup.NewIndex(),
+		Load:     amx.Read_Master,
+		Marker:   amx.AppConfig.GetString(constants.BuildMarker),
+		Poll:     amx.AppConfig.GetDuration(constants.ServePoll),
+		PageSize: amx.AppConfig.GetInt(constants.ServePageSize),
+	}
+
+	if err := server.ListenAndServe(ctx, amx.AppConfig.GetString(constants.ServeAddress)); err != nil {
+		return dbError("serve", "Lookup service stopped", err)
+	}
+	return nil
+}
+
+// Mark_Build records a successful build in the build marker file, which
+// running lookup services watch to reload their index.
+func (amx *AMXConfig) Mark_Build() error {
+
+	marker := amx.AppConfig.GetString(constants.BuildMarker)
+	if marker == "" {
+		return nil
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{"runId": amx.RunID, "completedAt": time.Now()})
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err != nil {
+		return fileError("mark build", "Unable to write build marker "+marker, err)
+	}
+
+	tmp := marker + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fileError("mark build", "Unable to write build marker "+marker, err)
+	}
+	if err := os.Rename(tmp, marker); err != nil {
+		return fileError("mark build", "Unable to write build marker "+marker, err)
+	}
+	return nil
+}
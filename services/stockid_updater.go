@@ -2,99 +2,81 @@ package services
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 
 	"github.com/rs/zerolog/log"
-	"main.go/constants"
-	"main.go/persistance/mssql"
+	"main.go/persistance"
 )
 
-func (amx *AMXConfig) UpdateStockID() {
-
-	log.Info().Msg("Updating Stock ID Details...")
-
-	var db *sql.DB
-	var err error
-
-	db, err = amx.MSSQLEntities.GetDBConnection()
-	if err != nil {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = err.Error()
-		amx.Log.Details = "MSSQL - Failed to create connection"
-		amx.LogStatus()
-	}
+// StockIDReport counts the outcome of a stock id update. Matched and
+// Unmatched count the Mojo ISINs with and without a scrip in the live
+// master. Of the matched ISINs the store applied Updated and failed on
+// Failed.
+type StockIDReport struct {
+	Received  int
+	Skipped   int
+	Matched   int
+	Unmatched int
+	Updated   int
+	Failed    int
+}
 
-	if !amx.MSSQLEntities.MssqlConnCheck(db) {
-		amx.Log.IsDBFailed = true
-		amx.Log.FailureMessage = "MSSQL Reconnect attepmts has been failed"
-		amx.Log.Details = "MSSQL - Connection Inactive"
-		amx.LogStatus()
-	}
+func (amx *AMXConfig) UpdateStockID() error {
 
-	defer mssql.CloseDBConnection(db)
+	_, err := amx.Update_StockIDs(context.Background())
+	return err
+}
 
-	url := amx.UrlConfig.GetString(amx.AppConfig.GetString(constants.Env) + "." + constants.StockMasterUrl)
-	client := http.Client{}
-	req, _ := http.NewRequest("GET", url, nil)
-	response, httpErr := client.Do(req)
-	if httpErr != nil {
+// Update_StockIDs links the Mojo stock ids to the scrips of the live master
+// with the same ISIN. Stocks without an ISIN are skipped, ISINs that are not
+// in the master are not sent to the store. It fails when the store could
+// not apply every id.
+func (amx *AMXConfig) Update_StockIDs(ctx context.Context) (StockIDReport, error) {
 
-		log.Error().Stringer("Requesting Url : ", req.URL).Err(httpErr).Msg("Mojo API Has Been Failed")
-		amx.Log.IsAPIFailed = true
-		amx.Log.FailureMessage = httpErr.Error()
-		amx.Log.Details = "Mojo api has been failed"
-		amx.Log.Url = url
-		amx.LogStatus()
+	log.Info().Msg("Updating Stock ID Details...")
 
+	var report StockIDReport
+	stocks, err := amx.mojo.StockMaster(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Mojo API Has Been Failed")
+		return report, apiError("stock id", "Mojo api has been failed", err)
 	}
+	report.Received = len(stocks)
 
-	res, _ := io.ReadAll(response.Body)
-	log.Info().Stringer("Requesting Url", req.URL).RawJSON("Response", res).Msg("")
-	var apiRes map[string]interface{}
-	json.Unmarshal(res, &apiRes)
-
-	if apiRes["message"] != "Success" {
-
-		amx.Log.IsAPIFailed = true
-		amx.Log.FailureMessage = apiRes["message"].(string)
-		amx.Log.Details = "Mojo api has been failed"
-		amx.Log.Url = url
-		amx.LogStatus()
+	master, err := amx.Store.Snapshot(ctx)
+	if err != nil {
+		return report, dbError("stock id", "Unable to read the live master", err)
 	}
-
-	var data []map[string]interface{}
-	data = apiRes["data"].(map[string]interface{})["stock_master"].([]map[string]interface{})
-	for index := 0; index < len(data); index++ {
-		sid := data[index]["sid"].(string)
-		if sid == "null" {
-			sid = ""
+	isins := make(map[string]bool, len(master))
+	for _, r := range master {
+		if r.ISIN != "" {
+			isins[r.ISIN] = true
 		}
-		isin := data[index]["isin"].(string)
-		if isin == "null" {
-			isin = ""
-		}
-		if len(isin) == 0 {
-			// Skipping
-			continue
-		}
-
-		sQuery := amx.DBConfig.GetString(constants.StockIDQuery)
-		tsql := fmt.Sprintf(
-			sQuery,
-			sid,
-			isin)
-
-		ctx := context.Background()
-		_, qErr := db.ExecContext(ctx, tsql)
+	}
 
-		if qErr != nil {
-			log.Error().Str("Query", tsql).Err(qErr).Msg("Error In Stock Id Updation")
+	var ids []persistance.StockID
+	for _, stock := range stocks {
+		switch {
+		case stock.ISIN == "":
+			report.Skipped++
+		case isins[stock.ISIN]:
+			report.Matched++
+			ids = append(ids, persistance.StockID{SID: stock.SID, ISIN: stock.ISIN})
+		default:
+			report.Unmatched++
 		}
 	}
 
-	log.Info().Msg("Stock ID Updated")
+	result, err := amx.Store.UpdateStockIDs(ctx, ids)
+	report.Updated, report.Failed = result.Updated, result.Failed
+	if err != nil {
+		return report, dbError("stock id", "Stock id update failed", err)
+	}
+	if report.Failed > 0 {
+		log.Error().Int("Updated", report.Updated).Int("Failed", report.Failed).Msg("Stock ID update incomplete")
+		return report, dbError("stock id", "Stock id update failed", fmt.Errorf("%d of %d stock ids not updated", report.Failed, len(ids)))
+	}
+
+	log.Info().Int("Received", report.Received).Int("Skipped", report.Skipped).Int("Matched", report.Matched).Int("Unmatched", report.Unmatched).Int("Updated", report.Updated).Msg("Stock ID Updated")
+	return report, nil
 }
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// Write_Summary prints the received, skipped and loaded counts per segment
+// followed by the skip count per reason.
+func Write_Summary(w io.Writer, tallies []*Tally) {
+
+	out := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer out.Flush()
+
+	fmt.Fprintln(out, "SEGMENT\tRECEIVED\tSKIPPED\tINSERTS")
+	var received, skipped, inserts int
+	for _, t := range tallies {
+		fmt.Fprintf(out, "%s\t%d\t%d\t%d\n", t.Segment, t.Received, t.Skipped, t.Rows)
+		received += t.Received
+		skipped += t.Skipped
+		inserts += t.Rows
+	}
+	fmt.Fprintf(out, "total\t%d\t%d\t%d\n", received, skipped, inserts)
+
+	if skipped == 0 {
+		return
+	}
+
+	fmt.Fprintln(out, "\nSEGMENT\tSKIP REASON\tCOUNT")
+	for _, t := range tallies {
+		reasons := make([]string, 0, len(t.Reasons))
+		for reason := range t.Reasons {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			fmt.Fprintf(out, "%s\t%s\t%d\n", t.Segment, reason, t.Reasons[reason])
+		}
+	}
+}
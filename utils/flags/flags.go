@@ -7,8 +7,50 @@ import (
 
 var (
 	baseConfigPath = flag.String(constants.BaseConfigPathKey, constants.BaseConfigPathDefaultValue, constants.BaseConfigPathUsage)
+	assetClass     = flag.String(constants.AssetClassKey, constants.AssetClassDefaultValue, constants.AssetClassUsage)
+	generation     = flag.String(constants.GenerationKey, "", constants.GenerationUsage)
+	dryRun         = flag.Bool(constants.DryRunKey, false, constants.DryRunUsage)
+	force          = flag.Bool(constants.ForceKey, false, constants.ForceUsage)
+	fromArchive    = flag.String(constants.FromArchiveKey, "", constants.FromArchiveUsage)
 )
 
+func Parse() {
+	flag.Parse()
+}
+
 func BaseConfigPath() string {
 	return *baseConfigPath
 }
+
+// Command returns the subcommand given on the command line, build by default.
+func Command() string {
+	if flag.NArg() == 0 {
+		return constants.BuildCommand
+	}
+	return flag.Arg(0)
+}
+
+// CommandArg returns the n-th argument following the subcommand.
+func CommandArg(n int) string {
+	return flag.Arg(n + 1)
+}
+
+func AssetClass() string {
+	return *assetClass
+}
+
+func Generation() string {
+	return *generation
+}
+
+func DryRun() bool {
+	return *dryRun
+}
+
+func Force() bool {
+	return *force
+}
+
+func FromArchive() string {
+	return *fromArchive
+}